@@ -0,0 +1,142 @@
+package httpdeadline
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// An Outcome classifies the decision the middleware made about a request's
+// deadline.
+type Outcome int
+
+const (
+	// OutcomeAbsent means that the request carried no deadline, so it was
+	// passed through unchanged.
+	OutcomeAbsent Outcome = iota
+	// OutcomeApplied means that the client's deadline was applied as-is.
+	OutcomeApplied
+	// OutcomeClamped means that the client's deadline was applied but
+	// tightened by policy (e.g., [WithMaxDeadline]).
+	OutcomeClamped
+	// OutcomeDefault means that the request carried no deadline, so the
+	// server's default (e.g., [WithDefaultDeadline]) was applied.
+	OutcomeDefault
+	// OutcomeUnbounded means that a trusted client explicitly asked for no
+	// deadline (see [WithUnboundedSentinel]), so the request was passed through
+	// without one.
+	OutcomeUnbounded
+	// OutcomeRejected means that the request was turned away.
+	OutcomeRejected
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeAbsent:
+		return "absent"
+	case OutcomeApplied:
+		return "applied"
+	case OutcomeClamped:
+		return "clamped"
+	case OutcomeDefault:
+		return "default"
+	case OutcomeUnbounded:
+		return "unbounded"
+	case OutcomeRejected:
+		return "rejected"
+	default:
+		return "unknown"
+	}
+}
+
+// An AuditRecord describes one deadline decision in full.  One is produced for
+// every request that the middleware handles, regardless of outcome.
+type AuditRecord struct {
+	// Time is when the decision was made.
+	Time time.Time
+	// Method and Path identify the request.
+	Method, Path string
+	// CorrelationID is the value of the header named by
+	// [WithCorrelationHeader], if any.
+	CorrelationID string
+	// Value is the raw deadline value the client sent.
+	Value string
+	// Source is where Value was found.  It is zero if the client sent none or
+	// the handler has no Source (see [From]).
+	Source Source
+	// Requested is the deadline the client asked for.  It is zero if the
+	// client sent none or it could not be parsed.
+	Requested time.Time
+	// Effective is the deadline applied to the request.  It is zero if none
+	// was applied.
+	Effective time.Time
+	// Outcome classifies the decision.
+	Outcome Outcome
+	// Err is the reason for rejection when Outcome is OutcomeRejected.  It
+	// matches one of the package's Err values with [errors.Is].
+	Err error
+
+	// deprecated reports whether the deadline came from a source and format
+	// deprecated with WithDeprecationWarning.
+	deprecated bool
+	// granted is the deadline that policy clamped the client's to before the
+	// request was rejected anyway, if it was.
+	granted time.Time
+}
+
+func (r AuditRecord) rejected(err error) AuditRecord {
+	if r.Outcome == OutcomeClamped {
+		r.granted = r.Effective
+	}
+	r.Outcome, r.Err, r.Effective = OutcomeRejected, err, time.Time{}
+	return r
+}
+
+// audit reports rec, decided for the request whose context is ctx.
+func (c *config) audit(ctx context.Context, rec AuditRecord) {
+	for _, sink := range c.auditSinks {
+		sink(rec)
+	}
+	if c.logger != nil {
+		c.log(ctx, rec)
+	}
+}
+
+// WithAuditSink registers sink to receive an [AuditRecord] for every request
+// the middleware handles.  Sinks are invoked synchronously in the order they
+// were registered, so they should be fast.
+func WithAuditSink(sink func(AuditRecord)) Option {
+	if sink == nil {
+		return invalidf("nil audit sink")
+	}
+	return optionFunc(func(c *config) { c.auditSinks = append(c.auditSinks, sink) })
+}
+
+// WithCorrelationHeader names the HTTP header whose value is recorded as
+// [AuditRecord.CorrelationID].
+func WithCorrelationHeader(name string) Option {
+	if name == "" {
+		return invalidf("empty correlation header name")
+	}
+	return optionFunc(func(c *config) { c.correlationHeader = name })
+}
+
+// WithEventChannel sends an [AuditRecord] for every request the middleware
+// handles on ch, decoupling aggregation from request handling.  Sends never
+// block: records are dropped when ch is full, and counted in dropped unless it
+// is nil.  Size ch's buffer for bursts, and drain it promptly.  Give each
+// channel its own counter to tell which falls behind.
+func WithEventChannel(ch chan<- AuditRecord, dropped *atomic.Uint64) Option {
+	if ch == nil {
+		return invalidf("nil event channel")
+	}
+	return WithAuditSink(func(rec AuditRecord) {
+		select {
+		case ch <- rec:
+		default:
+			if dropped != nil {
+				dropped.Add(1)
+			}
+		}
+	})
+}
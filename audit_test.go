@@ -0,0 +1,170 @@
+package httpdeadline
+
+import (
+	"errors"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithAuditSink(t *testing.T) {
+	far := time.Now().Add(time.Hour).Truncate(time.Second)
+	for _, test := range []struct {
+		Name string
+
+		Value   *string
+		Outcome Outcome
+		Err     error
+		Parsed  bool
+		Clamped bool
+	}{
+		{
+			Name:    "absent",
+			Outcome: OutcomeAbsent,
+		},
+		{
+			Name:    "applied",
+			Value:   ptr(asTimeFormat(now)),
+			Outcome: OutcomeApplied,
+			Parsed:  true,
+		},
+		{
+			Name:    "clamped",
+			Value:   ptr(asTimeFormat(far)),
+			Outcome: OutcomeClamped,
+			Parsed:  true,
+			Clamped: true,
+		},
+		{
+			Name:    "empty",
+			Value:   ptr(""),
+			Outcome: OutcomeRejected,
+			Err:     ErrEmptyValue,
+		},
+		{
+			Name:    "malformed",
+			Value:   ptr("garbage"),
+			Outcome: OutcomeRejected,
+			Err:     ErrParse,
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var recs []AuditRecord
+			h := FromHeader("X-MTP-Deadline", new(spyHandler),
+				WithMaxDeadline(time.Minute),
+				WithCorrelationHeader("X-Request-ID"),
+				WithAuditSink(func(rec AuditRecord) { recs = append(recs, rec) }))
+			req := httptest.NewRequest("POST", "/teapotz", nil)
+			req.Header.Set("X-Request-ID", "abc123")
+			if test.Value != nil {
+				req.Header.Set("X-MTP-Deadline", *test.Value)
+			}
+			before := time.Now()
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got, want := len(recs), 1; got != want {
+				t.Fatalf("len(recs) = %v, want %v", got, want)
+			}
+			rec := recs[0]
+			if rec.Time.Before(before) || rec.Time.After(time.Now()) {
+				t.Errorf("rec.Time = %v, want around %v", rec.Time, before)
+			}
+			if got, want := rec.Method, "POST"; got != want {
+				t.Errorf("rec.Method = %v, want %v", got, want)
+			}
+			if got, want := rec.Path, "/teapotz"; got != want {
+				t.Errorf("rec.Path = %v, want %v", got, want)
+			}
+			if got, want := rec.CorrelationID, "abc123"; got != want {
+				t.Errorf("rec.CorrelationID = %v, want %v", got, want)
+			}
+			if test.Value != nil {
+				if got, want := rec.Value, *test.Value; got != want {
+					t.Errorf("rec.Value = %q, want %q", got, want)
+				}
+			}
+			if got, want := rec.Outcome, test.Outcome; got != want {
+				t.Errorf("rec.Outcome = %v, want %v", got, want)
+			}
+			if !errors.Is(rec.Err, test.Err) || (test.Err == nil) != (rec.Err == nil) {
+				t.Errorf("rec.Err = %v, want %v", rec.Err, test.Err)
+			}
+			if got, want := !rec.Requested.IsZero(), test.Parsed; got != want {
+				t.Errorf("rec.Requested = %v, want set %v", rec.Requested, want)
+			}
+			switch {
+			case test.Clamped:
+				if got, want := rec.Effective, rec.Time.Add(time.Minute); !got.Equal(want) {
+					t.Errorf("rec.Effective = %v, want %v", got, want)
+				}
+			case test.Parsed:
+				if got, want := rec.Effective, rec.Requested; !got.Equal(want) {
+					t.Errorf("rec.Effective = %v, want %v", got, want)
+				}
+			default:
+				if !rec.Effective.IsZero() {
+					t.Errorf("rec.Effective = %v, want zero", rec.Effective)
+				}
+			}
+		})
+	}
+}
+
+func TestOutcomeString(t *testing.T) {
+	for o, want := range map[Outcome]string{
+		OutcomeAbsent:    "absent",
+		OutcomeApplied:   "applied",
+		OutcomeClamped:   "clamped",
+		OutcomeDefault:   "default",
+		OutcomeUnbounded: "unbounded",
+		OutcomeRejected:  "rejected",
+		Outcome(-1):      "unknown",
+	} {
+		if got := o.String(); got != want {
+			t.Errorf("Outcome(%d).String() = %q, want %q", o, got, want)
+		}
+	}
+}
+
+func TestWithEventChannel(t *testing.T) {
+	var (
+		ch      = make(chan AuditRecord, 2)
+		dropped atomic.Uint64
+	)
+	h := FromHeader("X-MTP-Deadline", new(spyHandler), WithEventChannel(ch, &dropped))
+	serve := func(val string) {
+		req := httptest.NewRequest("GET", "/teapotz", nil)
+		req.Header.Set("X-MTP-Deadline", val)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	serve(asTimeFormat(now))
+	serve("garbage")
+	if got, want := dropped.Load(), uint64(0); got != want {
+		t.Errorf("dropped = %v, want %v", got, want)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		serve(asTimeFormat(now)) // The channel is full.
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler blocked on full event channel")
+	}
+	if got, want := dropped.Load(), uint64(1); got != want {
+		t.Errorf("dropped = %v, want %v", got, want)
+	}
+	for _, want := range []struct {
+		Outcome Outcome
+		Err     error
+	}{
+		{Outcome: OutcomeApplied},
+		{Outcome: OutcomeRejected, Err: ErrParse},
+	} {
+		rec := <-ch
+		if rec.Outcome != want.Outcome || !errors.Is(rec.Err, want.Err) || rec.Path != "/teapotz" {
+			t.Errorf("event = %+v, want outcome %v with error %v for /teapotz", rec, want.Outcome, want.Err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package httpdeadline
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ParseBaggage parses a W3C Baggage HTTP header value like
+// "tenant=acme,deadline=Mon%2C%2022%20Jul%202024%2020%3A10%3A00%20GMT;ttl=1"
+// into its members' keys and percent-decoded values.  Member properties (after
+// ";") are discarded.
+func ParseBaggage(value string) (map[string]string, error) {
+	members := make(map[string]string)
+	for _, member := range strings.Split(value, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		if props := strings.IndexByte(member, ';'); props >= 0 {
+			member = member[:props]
+		}
+		key, val, ok := strings.Cut(member, "=")
+		if !ok {
+			return nil, fmt.Errorf("httpdeadline: baggage member %q lacks value", member)
+		}
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		if !isToken(key) {
+			return nil, fmt.Errorf("httpdeadline: invalid baggage key %q", key)
+		}
+		decoded, err := url.PathUnescape(val)
+		if err != nil {
+			return nil, fmt.Errorf("httpdeadline: invalid baggage value for %q: %v", key, err)
+		}
+		members[key] = decoded
+	}
+	return members, nil
+}
+
+// isToken reports whether s is an RFC 9110 token.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// WithBaggageMember treats the value of the middleware's source as a W3C
+// Baggage list and takes the deadline from its member named key, as in
+// FromHeader("baggage", h, WithBaggageMember("deadline")).  Requests whose
+// baggage lacks the member are treated as carrying no deadline; malformed
+// baggage or member values are rejected.
+func WithBaggageMember(key string) Option {
+	if !isToken(key) {
+		return invalidf("invalid baggage key %q", key)
+	}
+	return optionFunc(func(c *config) { c.baggageMember = key })
+}
+
+// fromBaggage extracts the configured baggage member from val.
+func (c *config) fromBaggage(val string) (string, bool, error) {
+	members, err := ParseBaggage(val)
+	if err != nil {
+		return "", false, err
+	}
+	member, ok := members[c.baggageMember]
+	return member, ok, nil
+}
@@ -0,0 +1,94 @@
+package httpdeadline
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseBaggage(t *testing.T) {
+	for _, test := range []struct {
+		Value string
+
+		Members map[string]string
+		Err     bool
+	}{
+		{Value: "", Members: map[string]string{}},
+		{Value: "a=1", Members: map[string]string{"a": "1"}},
+		{Value: "a=1, b = 2 ;prop;ttl=3 ,c=%20x%2C", Members: map[string]string{"a": "1", "b": "2", "c": " x,"}},
+		{Value: "a=1,,b=2", Members: map[string]string{"a": "1", "b": "2"}},
+		{Value: "a", Err: true},
+		{Value: "=1", Err: true},
+		{Value: "a b=1", Err: true},
+		{Value: "a=%zz", Err: true},
+	} {
+		members, err := ParseBaggage(test.Value)
+		if (err != nil) != test.Err {
+			t.Errorf("ParseBaggage(%q) err = %v, want error %v", test.Value, err, test.Err)
+			continue
+		}
+		if !test.Err && !reflect.DeepEqual(members, test.Members) {
+			t.Errorf("ParseBaggage(%q) = %v, want %v", test.Value, members, test.Members)
+		}
+	}
+}
+
+func TestWithBaggageMember(t *testing.T) {
+	encoded := url.PathEscape(asTimeFormat(now))
+	for _, test := range []struct {
+		Name string
+
+		Baggage *string
+
+		Status int
+		OK     bool
+	}{
+		{
+			Name:    "absent",
+			Baggage: nil,
+			Status:  200,
+		},
+		{
+			Name:    "member-absent",
+			Baggage: ptr("userId=alice,serverNode=DF%2028,isProduction=false"),
+			Status:  200,
+		},
+		{
+			Name:    "member-present",
+			Baggage: ptr("userId=alice,deadline=" + encoded + ";origin=edge,serverNode=DF%2028,deadlinez=garbage"),
+			Status:  200,
+			OK:      true,
+		},
+		{
+			Name:    "member-malformed",
+			Baggage: ptr("userId=alice,deadline=garbage"),
+			Status:  400,
+		},
+		{
+			Name:    "baggage-malformed",
+			Baggage: ptr("userId,deadline=" + encoded),
+			Status:  400,
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var spy spyHandler
+			h := FromHeader("baggage", &spy, WithBaggageMember("deadline"))
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.Baggage != nil {
+				req.Header.Set("Baggage", *test.Baggage)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, test.Status; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+			if got, want := spy.OK, test.OK; got != want {
+				t.Errorf("spy.OK = %v, want %v", got, want)
+			}
+			if test.OK && !spy.Deadline.Equal(now) {
+				t.Errorf("spy.Deadline = %v, want %v", spy.Deadline, now)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package httpdeadline
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrBodyDeadline indicates that reading the request body was cut off by the
+// request's deadline.
+var ErrBodyDeadline = errors.New("httpdeadline: request body read past deadline")
+
+// WithMultipartReadDeadline bounds reads of multipart request bodies (e.g.,
+// multipart/form-data uploads) by the request's deadline.  Cancelling the
+// request's context does not interrupt a read that is blocked on a slow client,
+// so a handler streaming parts with [http.Request.MultipartReader] could
+// otherwise hang well past the deadline.  With this option, the connection's
+// read deadline is set to the request's deadline (via
+// [http.ResponseController.SetReadDeadline]), and reads that fail after the
+// deadline return an error matching [ErrBodyDeadline].
+//
+// Handlers that parse the whole body up front with
+// [http.Request.ParseMultipartForm] are bounded the same way and see the error
+// from that call.
+func WithMultipartReadDeadline() Option {
+	return optionFunc(func(c *config) { c.multipartDeadline = true })
+}
+
+// WithExpectContinueMinBudget rejects requests that carry "Expect:
+// 100-continue" when their client-provided budget (the time between the
+// request's receipt and its deadline, after any capping) is under d, with an
+// error matching [ErrBudgetTooSmall].  The rejection happens before the body is
+// requested: [http.Server] only sends "100 Continue" once the handler first
+// reads the body, so the client receives the final response instead and need
+// not send a large body that could not be processed in time anyway.  Requests
+// without the expectation are unaffected; see [WithMinimumServiceTime] to
+// reject those.
+func WithExpectContinueMinBudget(d time.Duration) Option {
+	if d <= 0 {
+		return invalidf("non-positive expect-continue minimum budget")
+	}
+	return optionFunc(func(c *config) { c.expectContinueMin = d })
+}
+
+func expectsContinue(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get("Expect"), "100-continue")
+}
+
+func isMultipart(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && strings.HasPrefix(mediaType, "multipart/")
+}
+
+// boundBody bounds reads of req's body by deadline.
+func boundBody(w http.ResponseWriter, req *http.Request, deadline time.Time) {
+	// Not all ResponseWriters support read deadlines (e.g., HTTP/2 before Go
+	// 1.20 or test recorders); the body wrapper still reports late reads.
+	_ = http.NewResponseController(w).SetReadDeadline(deadline)
+	req.Body = &deadlineBody{ReadCloser: req.Body, deadline: deadline}
+}
+
+type deadlineBody struct {
+	io.ReadCloser
+	deadline time.Time
+}
+
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	if !time.Now().Before(b.deadline) {
+		return 0, ErrBodyDeadline
+	}
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && !time.Now().Before(b.deadline) {
+		err = fmt.Errorf("%w: %v", ErrBodyDeadline, err)
+	}
+	return n, err
+}
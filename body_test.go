@@ -0,0 +1,199 @@
+package httpdeadline
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithMultipartReadDeadline(t *testing.T) {
+	type result struct {
+		err     error
+		elapsed time.Duration
+	}
+	results := make(chan result, 1)
+	h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		mr, err := req.MultipartReader()
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		part, err := mr.NextPart()
+		if err == nil {
+			_, err = io.ReadAll(part)
+		}
+		results <- result{err: err, elapsed: time.Since(start)}
+	}), WithLayout(time.RFC3339Nano), WithMultipartReadDeadline())
+	srv := newServer(t, h)
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	mw := multipart.NewWriter(pw)
+	req, err := http.NewRequest("POST", srv.URL, pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("X-MTP-Deadline", time.Now().Add(200*time.Millisecond).Format(time.RFC3339Nano))
+	go func() {
+		// Send the start of one part and then stall like a slow client.
+		part, err := mw.CreateFormFile("upload", "upload.bin")
+		if err == nil {
+			part.Write([]byte("partial"))
+		}
+	}()
+	go func() {
+		if resp, err := newClient().Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	select {
+	case res := <-results:
+		if !errors.Is(res.err, ErrBodyDeadline) {
+			t.Errorf("reading part = %v, want %v", res.err, ErrBodyDeadline)
+		}
+		if res.elapsed > 2*time.Second {
+			t.Errorf("reading part took %v, want prompt failure", res.elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reading slow multipart body hung past deadline")
+	}
+}
+
+// spyBody records whether it was read.
+type spyBody struct {
+	io.Reader
+	read atomic.Bool
+}
+
+func (b *spyBody) Read(p []byte) (int, error) {
+	b.read.Store(true)
+	return b.Reader.Read(p)
+}
+
+func TestWithExpectContinueMinBudget(t *testing.T) {
+	h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.Copy(io.Discard, req.Body)
+	}), WithExpectContinueMinBudget(time.Minute))
+	srv := newServer(t, h)
+	for _, test := range []struct {
+		Name   string
+		Budget time.Duration
+		Expect bool
+
+		Code int
+		Read bool
+	}{
+		{Name: "tight", Budget: 10 * time.Second, Expect: true, Code: 400, Read: false},
+		{Name: "ample", Budget: time.Hour, Expect: true, Code: 200, Read: true},
+		{Name: "tight-without-expect", Budget: 10 * time.Second, Code: 200, Read: true},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			body := &spyBody{Reader: strings.NewReader(strings.Repeat("teapot", 1<<10))}
+			req, err := http.NewRequest("PUT", srv.URL, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("X-MTP-Deadline", asTimeFormat(time.Now().Add(test.Budget)))
+			if test.Expect {
+				req.Header.Set("Expect", "100-continue")
+			}
+			client := &http.Client{Transport: &http.Transport{ExpectContinueTimeout: 10 * time.Second}}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got, want := resp.StatusCode, test.Code; got != want {
+				t.Errorf("resp.StatusCode = %v, want %v", got, want)
+			}
+			if got, want := body.read.Load(), test.Read; got != want {
+				t.Errorf("body read = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// zeroBody is a request body of n zero bytes that counts how many were read.
+type zeroBody struct {
+	n    int64
+	read atomic.Int64
+}
+
+func (b *zeroBody) Read(p []byte) (int, error) {
+	remaining := b.n - b.read.Load()
+	if remaining <= 0 {
+		return 0, io.EOF
+	}
+	n := int(min(int64(len(p)), remaining))
+	clear(p[:n])
+	b.read.Add(int64(n))
+	return n, nil
+}
+
+func TestRejectUnreadBody(t *testing.T) {
+	const size = 256 << 20
+	var ran atomic.Bool
+	srv := newServer(t, FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ran.Store(true)
+		io.Copy(io.Discard, req.Body)
+	})))
+	for _, test := range []struct {
+		Name   string
+		Expect bool
+
+		MaxRead int64
+	}{
+		{Name: "streamed", MaxRead: size / 2},
+		{Name: "expect-continue", Expect: true, MaxRead: 0},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			body := &zeroBody{n: size}
+			req, err := http.NewRequest("PUT", srv.URL, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.ContentLength = size
+			req.Header.Set("X-MTP-Deadline", "garbage")
+			if test.Expect {
+				req.Header.Set("Expect", "100-continue")
+			}
+			client := &http.Client{Transport: &http.Transport{ExpectContinueTimeout: 10 * time.Second}}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+				t.Errorf("resp.StatusCode = %v, want %v", got, want)
+			}
+			if !resp.Close {
+				t.Error("resp.Close = false, want connection closed")
+			}
+			if got, limit := body.read.Load(), test.MaxRead; got > limit {
+				t.Errorf("client sent %v bytes of body, want at most %v", got, limit)
+			}
+		})
+	}
+	if ran.Load() {
+		t.Error("handler ran for rejected requests")
+	}
+}
+
+func TestRejectWithoutBody(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-MTP-Deadline", "garbage")
+	FromHeader("X-MTP-Deadline", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(rec, req)
+	if got := rec.Header().Get("Connection"); got != "" {
+		t.Errorf("Connection = %q, want unset", got)
+	}
+}
@@ -0,0 +1,89 @@
+package httpdeadline
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExhausted indicates that the server has granted as much budget as
+// its [BudgetBucket] allows for now (see [WithBudgetRateLimit]).
+var ErrBudgetExhausted = errors.New("httpdeadline: aggregate budget exhausted")
+
+// A BudgetBucket bounds the aggregate budget (the time between receipt and
+// deadline) that handlers grant per unit of time, so that a flood of requests
+// with long deadlines cannot collectively commit the server to more future
+// work than it can take on.  It is a token bucket whose tokens are budget:
+// each granted deadline draws its budget from the bucket, which refills at a
+// constant rate up to its capacity.  Attach it to handlers with
+// [WithBudgetRateLimit]; handlers sharing a BudgetBucket share its budget.
+type BudgetBucket struct {
+	capacity time.Duration
+	perSec   float64 // Refill rate in budget per second.
+
+	mu     sync.Mutex
+	tokens time.Duration
+	last   time.Time // When tokens was last refilled.
+}
+
+// NewBudgetBucket creates a full [BudgetBucket] holding up to capacity budget
+// that refills at refill per second of wall-clock time.  It panics if either
+// is not positive.
+func NewBudgetBucket(capacity, refill time.Duration) *BudgetBucket {
+	if capacity <= 0 || refill <= 0 {
+		panic(fmt.Sprintf("httpdeadline: non-positive budget bucket capacity %v or refill %v", capacity, refill))
+	}
+	return &BudgetBucket{capacity: capacity, perSec: float64(refill), tokens: capacity}
+}
+
+// take draws up to want from the bucket at now and reports how much it drew.
+func (b *BudgetBucket) take(now time.Time, want time.Duration) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = b.refilled(now)
+	if b.last.IsZero() || now.After(b.last) {
+		b.last = now
+	}
+	got := min(want, b.tokens)
+	b.tokens -= got
+	return got
+}
+
+// peek reports how much take would draw at now without drawing it.
+func (b *BudgetBucket) peek(now time.Time, want time.Duration) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return min(want, b.refilled(now))
+}
+
+// refilled reports the tokens in the bucket at now.  b.mu must be held.
+func (b *BudgetBucket) refilled(now time.Time) time.Duration {
+	if b.last.IsZero() || !now.After(b.last) {
+		return b.tokens
+	}
+	refilled := float64(b.tokens) + now.Sub(b.last).Seconds()*b.perSec
+	return time.Duration(min(refilled, float64(b.capacity)))
+}
+
+// refund returns d, drawn by take for a request that was rejected after all.
+func (b *BudgetBucket) refund(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.tokens+d, b.capacity)
+}
+
+// WithBudgetRateLimit draws the budget of each client deadline the handler
+// grants (after caps like [WithMaxDeadline]) from b.  Deadlines whose budget
+// exceeds what remains in b are clamped to the remainder and reported as
+// [OutcomeClamped]; once b is empty, requests with deadlines are rejected with
+// [http.StatusServiceUnavailable] and an error matching [ErrBudgetExhausted]
+// until it refills.  Requests without deadlines, including those that receive
+// defaults like [WithDefaultDeadline], draw nothing, and requests rejected
+// after drawing (e.g., per [WithMinimumServiceTime]) return what they drew.
+func WithBudgetRateLimit(b *BudgetBucket) Option {
+	if b == nil {
+		return invalidf("nil budget bucket")
+	}
+	return optionFunc(func(c *config) { c.budgetBucket = b })
+}
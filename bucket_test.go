@@ -0,0 +1,127 @@
+package httpdeadline
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithBudgetRateLimit(t *testing.T) {
+	clock := now
+	b := NewBudgetBucket(time.Minute, time.Second)
+	var got AuditRecord
+	var spy spyHandler
+	h := FromHeader("X-MTP-Deadline", &spy,
+		WithClock(func() time.Time { return clock }),
+		WithDefaultDeadline(time.Hour),
+		WithBudgetRateLimit(b),
+		WithAuditSink(func(rec AuditRecord) { got = rec }))
+	for _, test := range []struct {
+		Name    string
+		Advance time.Duration
+		Budget  time.Duration
+
+		Code     int
+		Outcome  Outcome
+		Deadline time.Time
+	}{
+		{Name: "first", Budget: 30 * time.Second, Code: 200, Outcome: OutcomeApplied, Deadline: now.Add(30 * time.Second)},
+		{Name: "default-draws-nothing", Code: 200, Outcome: OutcomeDefault, Deadline: now.Add(time.Hour)},
+		{Name: "second", Budget: 40 * time.Second, Code: 200, Outcome: OutcomeClamped, Deadline: now.Add(30 * time.Second)},
+		{Name: "exhausted", Budget: 10 * time.Second, Code: 503, Outcome: OutcomeRejected},
+		{Name: "refilled", Advance: 10 * time.Second, Budget: 30 * time.Second, Code: 200, Outcome: OutcomeClamped, Deadline: now.Add(20 * time.Second)},
+		{Name: "refilled-to-capacity", Advance: time.Hour, Budget: 50 * time.Second, Code: 200, Outcome: OutcomeApplied, Deadline: now.Add(time.Hour + 60*time.Second)},
+	} {
+		clock = clock.Add(test.Advance)
+		spy = spyHandler{}
+		req := httptest.NewRequest("GET", "/", nil)
+		if test.Budget > 0 {
+			req.Header.Set("X-MTP-Deadline", asTimeFormat(clock.Add(test.Budget)))
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if got, want := rec.Code, test.Code; got != want {
+			t.Errorf("%v: rec.Code = %v, want %v", test.Name, got, want)
+		}
+		if got, want := got.Outcome, test.Outcome; got != want {
+			t.Errorf("%v: rec.Outcome = %v, want %v", test.Name, got, want)
+		}
+		if test.Code == 503 && !errors.Is(got.Err, ErrBudgetExhausted) {
+			t.Errorf("%v: rec.Err = %v, want %v", test.Name, got.Err, ErrBudgetExhausted)
+		}
+		if got, want := spy.Deadline, test.Deadline; test.Code == 200 && !got.Equal(want) {
+			t.Errorf("%v: spy.Deadline = %v, want %v", test.Name, got, want)
+		}
+	}
+}
+
+func TestWithBudgetRateLimitRefund(t *testing.T) {
+	for _, test := range []struct {
+		Name   string
+		Opt    Option
+		Header http.Header
+	}{
+		{
+			Name: "minimum-service-time",
+			Opt:  WithMinimumServiceTime(func(*http.Request) time.Duration { return 2 * time.Minute }),
+		},
+		{
+			Name:   "expect-continue",
+			Opt:    WithExpectContinueMinBudget(2 * time.Minute),
+			Header: http.Header{"Expect": {"100-continue"}},
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			b := NewBudgetBucket(time.Minute, time.Second)
+			var got AuditRecord
+			h := FromHeader("X-MTP-Deadline", new(spyHandler),
+				WithClock(func() time.Time { return now }),
+				WithBudgetRateLimit(b),
+				test.Opt,
+				WithAuditSink(func(rec AuditRecord) { got = rec }))
+			req := httptest.NewRequest("POST", "/", nil)
+			for k, v := range test.Header {
+				req.Header[k] = v
+			}
+			req.Header.Set("X-MTP-Deadline", asTimeFormat(now.Add(time.Hour)))
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if !errors.Is(got.Err, ErrBudgetTooSmall) {
+				t.Fatalf("rec.Err = %v, want %v", got.Err, ErrBudgetTooSmall)
+			}
+			if got, want := b.take(now, time.Hour), time.Minute; got != want {
+				t.Errorf("bucket level after rejection = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestNewBudgetBucketPanics(t *testing.T) {
+	for _, test := range []struct {
+		Capacity, Refill time.Duration
+	}{
+		{Capacity: 0, Refill: time.Second},
+		{Capacity: time.Minute, Refill: 0},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewBudgetBucket(%v, %v) did not panic", test.Capacity, test.Refill)
+				}
+			}()
+			NewBudgetBucket(test.Capacity, test.Refill)
+		}()
+	}
+}
+
+func BenchmarkBudgetBucket(b *testing.B) {
+	bucket := NewBudgetBucket(time.Hour, time.Hour)
+	start := time.Now()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			bucket.take(start.Add(time.Since(start)), time.Millisecond)
+		}
+	})
+}
@@ -0,0 +1,40 @@
+package httpdeadline
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// RefreshDeadline prepares an outbound request for a retry by replacing a stale
+// deadline in its named header.  If the header holds a [http.ParseTime]-valid
+// deadline that has already passed, it is rewritten as budget from now in
+// [http.TimeFormat].  Otherwise, including when the header is absent or
+// malformed, the request is left untouched.
+//
+// Clients that blindly resend the original request's headers would otherwise
+// have their retries rejected or immediately cancelled by servers.
+func RefreshDeadline(req *http.Request, name string, budget time.Duration) {
+	deadline, err := http.ParseTime(req.Header.Get(name))
+	if err != nil {
+		return
+	}
+	if now := time.Now(); deadline.Before(now) {
+		req.Header.Set(name, now.Add(budget).UTC().Format(http.TimeFormat))
+	}
+}
+
+// Propagate sets the named header of the outbound request out to the deadline
+// of ctx, typically the context of an inbound request that [FromHeader] or
+// [FromQueryParams] handled, so that downstream services inherit the budget.
+// The deadline is formatted per [WithEmitFormat] among opts, so the same
+// options that configure the inbound middleware may be passed here.  If ctx has
+// no deadline, out is left untouched.
+func Propagate(ctx context.Context, out *http.Request, name string, opts ...Option) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+	cfg := mustConfig(opts)
+	out.Header.Set(name, cfg.format(deadline))
+}
@@ -0,0 +1,85 @@
+package httpdeadline
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRefreshDeadline(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	for _, test := range []struct {
+		Name string
+
+		Value *string
+
+		Refreshed bool
+	}{
+		{Name: "absent", Value: nil},
+		{Name: "malformed", Value: ptr("garbage")},
+		{Name: "valid", Value: ptr(asTimeFormat(future))},
+		{Name: "expired", Value: ptr(asTimeFormat(now)), Refreshed: true},
+		{Name: "expired-rfc850", Value: ptr(asRFC850(now)), Refreshed: true},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "http://example.com/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.Value != nil {
+				req.Header.Set("X-MTP-Deadline", *test.Value)
+			}
+			before := time.Now().Truncate(time.Second)
+			RefreshDeadline(req, "X-MTP-Deadline", time.Minute)
+			got := req.Header.Values("X-MTP-Deadline")
+			if !test.Refreshed {
+				if test.Value == nil && len(got) != 0 || test.Value != nil && (len(got) != 1 || got[0] != *test.Value) {
+					t.Errorf("header = %q, want untouched", got)
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("header = %q, want one value", got)
+			}
+			deadline, err := http.ParseTime(got[0])
+			if err != nil {
+				t.Fatalf("refreshed header %q does not parse: %v", got[0], err)
+			}
+			if low, high := before.Add(time.Minute), time.Now().Add(time.Minute); deadline.Before(low) || deadline.After(high) {
+				t.Errorf("refreshed deadline = %v, want within [%v, %v]", deadline, low, high)
+			}
+		})
+	}
+}
+
+func TestPropagate(t *testing.T) {
+	opts := []Option{WithLayout(time.RFC3339), WithEmitFormat(http.TimeFormat)}
+	var out *http.Request
+	h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		var err error
+		out, err = http.NewRequestWithContext(req.Context(), "GET", "http://backend.example/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		Propagate(req.Context(), out, "X-MTP-Deadline", opts...)
+	}), opts...)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-MTP-Deadline", now.Format(time.RFC3339))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got, want := rec.Code, 200; got != want {
+		t.Fatalf("rec.Code = %v, want %v", got, want)
+	}
+	if got, want := out.Header.Get("X-MTP-Deadline"), asTimeFormat(now); got != want {
+		t.Errorf("propagated header = %q, want %q", got, want)
+	}
+
+	out.Header.Del("X-MTP-Deadline")
+	Propagate(context.Background(), out, "X-MTP-Deadline", opts...)
+	if got, ok := out.Header["X-Mtp-Deadline"]; ok {
+		t.Errorf("propagated header = %q, want absent without deadline", got)
+	}
+}
@@ -0,0 +1,138 @@
+package httpdeadline
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+type deadlineKey struct{}
+
+// applied records the deadline the middleware settled on for a request.
+type applied struct {
+	effective time.Time
+	// requested is the client's deadline before policy, if it sent one.
+	requested time.Time
+	// soft is when WithSoftDeadline's func is due, if configured.
+	soft time.Time
+	// value is the raw client value the deadline came from, if any.
+	value string
+	// source is where value was found, if anywhere.
+	source Source
+	// outcome is how the deadline was determined.
+	outcome Outcome
+	// provisional reports whether the deadline awaits confirmation by
+	// PromoteDeadline.
+	provisional bool
+	// uncancelled reports whether the deadline does not cancel the request's
+	// context, as with WithAdvisoryOnly and WithStreamingMode.
+	uncancelled bool
+	// trailer is WithDeadlineTrailer's trailer name, if configured, and
+	// trailerEnabled whether the handler opted into it.
+	trailer        string
+	trailerEnabled atomic.Bool
+}
+
+func withApplied(ctx context.Context, a *applied) context.Context {
+	return context.WithValue(ctx, deadlineKey{}, a)
+}
+
+func appliedFrom(ctx context.Context) (*applied, bool) {
+	a, ok := ctx.Value(deadlineKey{}).(*applied)
+	return a, ok
+}
+
+// Deadline reports the deadline that this package's middleware settled on for
+// the request whose context is ctx: the client's deadline after policy like
+// [WithMaxDeadline], or a server default like [WithDefaultDeadline]'s.  It
+// also reports deadlines that do not cancel the request's context, as with
+// [WithAdvisoryOnly].  Unlike [context.Context.Deadline], it ignores deadlines
+// that originate elsewhere (e.g., from the server or other middleware), so it
+// reports false if the middleware settled on none.
+func Deadline(ctx context.Context) (time.Time, bool) {
+	a, ok := appliedFrom(ctx)
+	if !ok {
+		return time.Time{}, false
+	}
+	return a.effective, true
+}
+
+// RequestedDeadline reports the deadline that the client requested for the
+// request whose context is ctx, as parsed by this package's middleware before
+// policy like [WithMaxDeadline] applied.  It reports false if the client sent
+// no deadline (e.g., because a default applied).  Together with
+// [WithAdvisoryOnly], it lets handlers act on the client's deadline themselves.
+func RequestedDeadline(ctx context.Context) (time.Time, bool) {
+	a, ok := appliedFrom(ctx)
+	if !ok || a.requested.IsZero() {
+		return time.Time{}, false
+	}
+	return a.requested, true
+}
+
+// Remaining reports how much time remains before ctx's deadline, which lets
+// handlers degrade gracefully (e.g., by serving a cached response) when the
+// budget is tight.  It reports false if ctx has no deadline.  The result is
+// negative once the deadline has passed.
+func Remaining(ctx context.Context) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(deadline), true
+}
+
+// CopyDeadline returns a copy of dst that carries the deadline this package's
+// middleware applied to src, along with the metadata that [Deadline],
+// [SoftDeadline], and [LogAttrs] report, for handlers that re-dispatch work
+// under a freshly constructed request rather than one derived from the
+// inbound request (e.g., with [http.Request.WithContext]):
+//
+//	sub, _ := http.NewRequestWithContext(context.Background(), "GET", "/internal", nil)
+//	ctx, cancel := httpdeadline.CopyDeadline(sub.Context(), req.Context())
+//	defer cancel()
+//	router.ServeHTTP(w, sub.WithContext(ctx))
+//
+// The copy's deadline fires with [context.Cause] [ErrDeadlineExceeded],
+// unless the deadline did not cancel src either (see [WithAdvisoryOnly] and
+// [WithStreamingMode]), in which case only the metadata is copied.  If src
+// carries no such deadline, CopyDeadline returns dst unchanged.  Callers must
+// call the returned CancelFunc once done with the copy.
+func CopyDeadline(dst, src context.Context) (context.Context, context.CancelFunc) {
+	a, ok := appliedFrom(src)
+	if !ok {
+		return dst, func() {}
+	}
+	if a.uncancelled {
+		return withApplied(dst, a), func() {}
+	}
+	return context.WithDeadlineCause(withApplied(dst, a), a.effective, ErrDeadlineExceeded)
+}
+
+// DeriveBackgroundContext returns a context for background work that a
+// handler starts but that should outlive its request, like fire-and-forget
+// cache fills, while still honoring a budget related to the client's.  The
+// returned context is detached from ctx, the request's context: it keeps
+// ctx's values, but is not cancelled when ctx is, such as when the handler
+// returns or the client disconnects.  Instead, its deadline is the one this
+// package's middleware applied to ctx, extended by extend, and it fires with
+// [context.Cause] [ErrDeadlineExceeded].  [Deadline] reports the extended
+// deadline for it.
+//
+// If ctx carries no such deadline, or one that did not cancel it (see
+// [WithAdvisoryOnly] and [WithStreamingMode]), the returned context has no
+// deadline at all, so bound the work by other means.  Callers must call the returned
+// CancelFunc once the work is done.
+func DeriveBackgroundContext(ctx context.Context, extend time.Duration) (context.Context, context.CancelFunc) {
+	bg := context.WithoutCancel(ctx)
+	a, ok := appliedFrom(ctx)
+	if !ok {
+		return context.WithCancel(bg)
+	}
+	deadline := a.effective.Add(extend)
+	bg = withApplied(bg, &applied{effective: deadline, requested: a.requested, value: a.value, source: a.source, outcome: a.outcome, uncancelled: a.uncancelled})
+	if a.uncancelled {
+		return context.WithCancel(bg)
+	}
+	return context.WithDeadlineCause(bg, deadline, ErrDeadlineExceeded)
+}
@@ -0,0 +1,345 @@
+package httpdeadline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeadline(t *testing.T) {
+	var (
+		got time.Time
+		ok  bool
+	)
+	h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		got, ok = Deadline(req.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	ctx, cancel := context.WithTimeout(req.Context(), time.Hour)
+	defer cancel()
+	h.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+	if ok {
+		t.Errorf("Deadline() = %v, true; want false for foreign deadline", got)
+	}
+
+	req.Header.Set("X-MTP-Deadline", asTimeFormat(now))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if !ok || !got.Equal(now) {
+		t.Errorf("Deadline() = %v, %v; want %v, true", got, ok, now)
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	if d, ok := Remaining(context.Background()); ok {
+		t.Errorf("Remaining(context.Background()) = %v, true; want false", d)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if d, ok := Remaining(ctx); !ok || d <= 0 || d > time.Minute {
+		t.Errorf("Remaining(ctx) = %v, %v; want (0, 1m], true", d, ok)
+	}
+}
+
+func TestCopyDeadline(t *testing.T) {
+	deadline := time.Now().Add(time.Hour).Truncate(time.Second)
+	type observation struct {
+		ctxDeadline, deadline time.Time
+		ctxOK, ok             bool
+		outcome               string
+	}
+	observe := func(got *observation) http.Handler {
+		return http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+			ctx := req.Context()
+			got.ctxDeadline, got.ctxOK = ctx.Deadline()
+			got.deadline, got.ok = Deadline(ctx)
+			for _, a := range LogAttrs(ctx) {
+				if a.Key == AttrOutcome {
+					got.outcome = a.Value.String()
+				}
+			}
+		})
+	}
+	for _, test := range []struct {
+		Name     string
+		Dispatch func(inner http.Handler, w http.ResponseWriter, req *http.Request)
+	}{
+		{
+			Name: "derived-request",
+			Dispatch: func(inner http.Handler, w http.ResponseWriter, req *http.Request) {
+				sub := req.Clone(req.Context())
+				sub.URL.Path = "/internal"
+				inner.ServeHTTP(w, sub)
+			},
+		},
+		{
+			Name: "fresh-request",
+			Dispatch: func(inner http.Handler, w http.ResponseWriter, req *http.Request) {
+				sub := httptest.NewRequest("GET", "/internal", nil)
+				ctx, cancel := CopyDeadline(sub.Context(), req.Context())
+				defer cancel()
+				inner.ServeHTTP(w, sub.WithContext(ctx))
+			},
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var got observation
+			inner := observe(&got)
+			h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				test.Dispatch(inner, w, req)
+			}))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-MTP-Deadline", asTimeFormat(deadline))
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if !got.ctxOK || !got.ctxDeadline.Equal(deadline) {
+				t.Errorf("ctx.Deadline() = %v, %v; want %v, true", got.ctxDeadline, got.ctxOK, deadline)
+			}
+			if !got.ok || !got.deadline.Equal(deadline) {
+				t.Errorf("Deadline(ctx) = %v, %v; want %v, true", got.deadline, got.ok, deadline)
+			}
+			if got, want := got.outcome, "applied"; got != want {
+				t.Errorf("LogAttrs(ctx) outcome = %q, want %q", got, want)
+			}
+		})
+	}
+	t.Run("no-deadline", func(t *testing.T) {
+		type key struct{}
+		dst := context.WithValue(context.Background(), key{}, "teapot")
+		ctx, cancel := CopyDeadline(dst, context.Background())
+		defer cancel()
+		if ctx != dst {
+			t.Errorf("CopyDeadline(dst, context.Background()) = %v, want dst", ctx)
+		}
+	})
+}
+
+func TestDeriveBackgroundContext(t *testing.T) {
+	const extend = 100 * time.Millisecond
+	type result struct {
+		derivedAlive bool // When the request's context ended.
+		deadline     time.Time
+		ok           bool
+		expired      time.Time
+		cause        error
+	}
+	results := make(chan result, 1)
+	h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		bg, cancel := DeriveBackgroundContext(req.Context(), extend)
+		go func() {
+			defer cancel()
+			var r result
+			r.deadline, r.ok = Deadline(bg)
+			<-req.Context().Done()
+			r.derivedAlive = bg.Err() == nil
+			<-bg.Done()
+			r.expired, r.cause = time.Now(), context.Cause(bg)
+			results <- r
+		}()
+	}), WithLayout(time.RFC3339Nano))
+	deadline := time.Now().Add(50 * time.Millisecond)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-MTP-Deadline", deadline.Format(time.RFC3339Nano))
+	h.ServeHTTP(httptest.NewRecorder(), req) // Returning cancels the request's context.
+	r := <-results
+	if !r.derivedAlive {
+		t.Error("derived context ended with the request's context")
+	}
+	if want := deadline.Add(extend); !r.ok || !r.deadline.Equal(want) {
+		t.Errorf("Deadline(bg) = %v, %v; want %v, true", r.deadline, r.ok, want)
+	}
+	if r.expired.Before(deadline.Add(extend)) {
+		t.Errorf("derived context expired at %v, before %v", r.expired, deadline.Add(extend))
+	}
+	if got, want := r.cause, ErrDeadlineExceeded; got != want {
+		t.Errorf("context.Cause(bg) = %v, want %v", got, want)
+	}
+}
+
+func TestDeriveBackgroundContextWithoutDeadline(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	bg, cancel := DeriveBackgroundContext(parent, time.Second)
+	cancelParent()
+	if _, ok := bg.Deadline(); ok {
+		t.Error("bg.Deadline() reports a deadline, want none")
+	}
+	if bg.Err() != nil {
+		t.Errorf("bg.Err() = %v after parent cancellation, want nil", bg.Err())
+	}
+	cancel()
+	if bg.Err() == nil {
+		t.Error("bg.Err() = nil after cancel, want error")
+	}
+}
+
+func TestWithAdvisoryOnly(t *testing.T) {
+	for _, test := range []struct {
+		Name  string
+		Value string
+
+		Status    int
+		Requested time.Time
+		Effective time.Time
+		OK        bool
+	}{
+		{Name: "applied", Value: asTimeFormat(now.Add(time.Minute)), Status: 200, Requested: now.Add(time.Minute), Effective: now.Add(time.Minute), OK: true},
+		{Name: "clamped", Value: asTimeFormat(now.Add(time.Hour)), Status: 200, Requested: now.Add(time.Hour), Effective: now.Add(2 * time.Minute), OK: true},
+		{Name: "absent", Status: 200},
+		{Name: "malformed", Value: "garbage", Status: 400},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var (
+				ran                 bool
+				hasDeadline, reqOK  bool
+				requested, deadline time.Time
+				deadlineOK          bool
+			)
+			h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				ran = true
+				_, hasDeadline = req.Context().Deadline()
+				requested, reqOK = RequestedDeadline(req.Context())
+				deadline, deadlineOK = Deadline(req.Context())
+			}), WithClock(func() time.Time { return now }), WithMaxDeadline(2*time.Minute), WithAdvisoryOnly())
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.Value != "" {
+				req.Header.Set("X-MTP-Deadline", test.Value)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, test.Status; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+			if !ran {
+				return
+			}
+			if hasDeadline {
+				t.Error("request context has a deadline, want none")
+			}
+			if !requested.Equal(test.Requested) || reqOK != test.OK {
+				t.Errorf("RequestedDeadline() = %v, %v; want %v, %v", requested, reqOK, test.Requested, test.OK)
+			}
+			if !deadline.Equal(test.Effective) || deadlineOK != test.OK {
+				t.Errorf("Deadline() = %v, %v; want %v, %v", deadline, deadlineOK, test.Effective, test.OK)
+			}
+		})
+	}
+}
+
+func TestRequestedDeadlineDefault(t *testing.T) {
+	var ok bool
+	h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, ok = RequestedDeadline(req.Context())
+	}), WithDefaultDeadline(time.Minute))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if ok {
+		t.Error("RequestedDeadline() reports a deadline for a default, want none")
+	}
+}
+
+func TestWithAdvisoryOnlyEnforcement(t *testing.T) {
+	// The injected clock lies in the past, so the deadline has long passed in
+	// real time, which is what enforcement consults.
+	past := asTimeFormat(now.Add(time.Minute))
+	for _, test := range []struct {
+		Name    string
+		Opt     Option
+		Request func() *http.Request
+		// Enforced reports whether the deadline was enforced.
+		Enforced func(w http.ResponseWriter, req *http.Request) bool
+		// Rewritten reports whether the response was rewritten.
+		Rewritten func(rec *httptest.ResponseRecorder) bool
+	}{
+		{
+			Name: "multipart",
+			Opt:  WithMultipartReadDeadline(),
+			Request: func() *http.Request {
+				req := httptest.NewRequest("POST", "/", strings.NewReader("--x--\r\n"))
+				req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+				return req
+			},
+			Enforced: func(_ http.ResponseWriter, req *http.Request) bool {
+				_, err := io.ReadAll(req.Body)
+				return errors.Is(err, ErrBodyDeadline)
+			},
+		},
+		{
+			Name: "flush",
+			Opt:  WithFlushDeadlineEnforcement(),
+			Enforced: func(w http.ResponseWriter, _ *http.Request) bool {
+				return errors.Is(http.NewResponseController(w).Flush(), ErrDeadlineExceeded)
+			},
+		},
+		{
+			Name: "grpc-web",
+			Opt:  WithGRPCWebStatus(),
+			Rewritten: func(rec *httptest.ResponseRecorder) bool {
+				return rec.Header().Get("Grpc-Status") != ""
+			},
+		},
+	} {
+		for _, advisory := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%v/advisory=%v", test.Name, advisory), func(t *testing.T) {
+				opts := []Option{WithClock(func() time.Time { return now }), test.Opt}
+				if advisory {
+					opts = append(opts, WithAdvisoryOnly())
+				}
+				var enforced bool
+				h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					if test.Enforced != nil {
+						enforced = test.Enforced(w, req)
+					}
+				}), opts...)
+				req := httptest.NewRequest("GET", "/", nil)
+				if test.Request != nil {
+					req = test.Request()
+				}
+				req.Header.Set("X-MTP-Deadline", past)
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, req)
+				if test.Rewritten != nil {
+					enforced = test.Rewritten(rec)
+				}
+				if got, want := enforced, !advisory; got != want {
+					t.Errorf("deadline enforced = %v, want %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestWithAdvisoryOnlyContextHelpers(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		Opt  Option
+	}{
+		{Name: "advisory", Opt: WithAdvisoryOnly()},
+		{Name: "streaming", Opt: WithStreamingMode(func(*http.Request) {})},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var ctxs []context.Context
+			h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				copied, cancel := CopyDeadline(context.Background(), req.Context())
+				defer cancel()
+				bg, cancel := DeriveBackgroundContext(req.Context(), time.Minute)
+				defer cancel()
+				ctxs = append(ctxs, copied, bg)
+			}), WithClock(func() time.Time { return now }), test.Opt)
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-MTP-Deadline", asTimeFormat(now.Add(time.Minute)))
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			for i, ctx := range ctxs {
+				if _, ok := ctx.Deadline(); ok {
+					t.Errorf("context %d has a deadline, want none", i)
+				}
+				if _, ok := Deadline(ctx); !ok {
+					t.Errorf("Deadline(context %d) reports none, want one", i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package httpdeadline
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestResponseController verifies that handlers reach the underlying
+// connection through http.ResponseController the same with and without the
+// middleware, however it wraps their writers.
+func TestResponseController(t *testing.T) {
+	ops := []struct {
+		Name string
+		Do   func(rc *http.ResponseController) error
+	}{
+		{Name: "SetReadDeadline", Do: func(rc *http.ResponseController) error { return rc.SetReadDeadline(time.Now().Add(time.Minute)) }},
+		{Name: "SetWriteDeadline", Do: func(rc *http.ResponseController) error { return rc.SetWriteDeadline(time.Now().Add(time.Minute)) }},
+		{Name: "EnableFullDuplex", Do: func(rc *http.ResponseController) error { return rc.EnableFullDuplex() }},
+		{Name: "Flush", Do: func(rc *http.ResponseController) error { return rc.Flush() }},
+		{Name: "Hijack", Do: func(rc *http.ResponseController) error {
+			conn, buf, err := rc.Hijack()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			fmt.Fprint(buf, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\nConnection: close\r\n\r\nok")
+			return buf.Flush()
+		}},
+	}
+	wrappers := []struct {
+		Name string
+		Wrap func(http.Handler) http.Handler
+	}{
+		{Name: "none", Wrap: func(h http.Handler) http.Handler { return h }},
+		{Name: "plain", Wrap: func(h http.Handler) http.Handler {
+			return FromHeader("X-MTP-Deadline", h, WithLayout(time.RFC3339Nano))
+		}},
+		{Name: "wrapped-writers", Wrap: func(h http.Handler) http.Handler {
+			return FromHeader("X-MTP-Deadline", h,
+				WithLayout(time.RFC3339Nano),
+				WithGRPCWebStatus(),
+				WithOnFirstWrite(func(*http.Request, time.Duration, time.Duration) {}),
+				WithDeadlineTrailer("X-MTP-Deadline-Remaining"),
+				WithFlushDeadlineEnforcement(),
+				WithResponseWriterReporting())
+		}},
+	}
+	for _, op := range ops {
+		for _, wrapper := range wrappers {
+			t.Run(op.Name+"/"+wrapper.Name, func(t *testing.T) {
+				errs := make(chan error, 1)
+				h := wrapper.Wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					err := op.Do(http.NewResponseController(w))
+					errs <- err
+					if err == nil && op.Name != "Hijack" {
+						io.WriteString(w, "ok")
+					}
+				}))
+				srv := newServer(t, h)
+				req := newGetRequest(t, urlOf(t, srv))
+				req.Header.Set("X-MTP-Deadline", time.Now().Add(time.Minute).Format(time.RFC3339Nano))
+				resp, err := newClient().Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+				if err := <-errs; err != nil {
+					t.Fatalf("%v: %v", op.Name, err)
+				}
+				body, err := io.ReadAll(resp.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, want := string(body), "ok"; got != want {
+					t.Errorf("body = %q, want %q", got, want)
+				}
+			})
+		}
+	}
+}
@@ -7,7 +7,8 @@
 // # Usage
 //
 // Wrap the outermost [http.Handler] that you will register with the
-// [http.ServeMux] with either [FromHeader] or [FromQueryParams].
+// [http.ServeMux] with either [FromHeader], [FromQueryParams], or
+// [FromPathValue] (or [From] for deadlines found elsewhere in the request).
 //
 //	var mux http.ServeMux
 //	mux.Handle("/teapotz", httpdeadline.FromHeader("X-MTP-Deadline", teapotz))
@@ -23,6 +24,36 @@
 // The same principles described above with [FromHeader] apply to
 // [FromQueryParams].
 //
+// # Options
+//
+// Both constructors accept [Option] values that refine how client-provided
+// deadlines are treated.  For instance, [WithMaxDeadline] bounds how far into
+// the future a client may push the deadline:
+//
+//	mux.Handle("/teapotz", httpdeadline.FromHeader("X-MTP-Deadline", teapotz,
+//		httpdeadline.WithMaxDeadline(10*time.Second)))
+//
+// # Protocols
+//
+// The middleware only inspects the [http.Request] and its context, so it
+// behaves identically whatever protocol the request arrived over: HTTP/1.x,
+// HTTP/2, or HTTP/3 with servers that implement [http.Handler].  In
+// particular, stream and datagram handling that derives from the request's
+// context is bounded by the same deadline, and [Deadline] reports it.
+//
+// # Timeouts
+//
+// The middleware composes with [http.TimeoutHandler] in either order: the
+// wrapped handler's context ends at the earlier of the client's deadline and
+// the timeout.  The order decides the response, however.  With
+// TimeoutHandler inside the middleware, it answers
+// [http.StatusServiceUnavailable] whichever ends first, as it cannot tell
+// the client's deadline from its own timeout.  With TimeoutHandler outside,
+// it answers so only when its own timeout ends first; when the client's
+// deadline does, the response is whatever the wrapped handler wrote (see
+// [WatchdogHandler] to answer [http.StatusGatewayTimeout] instead).  Prefer
+// the latter order.
+//
 // # Environmental Considerations
 //
 // Consider where this package is used and whether it is in a public or private
@@ -34,6 +65,12 @@
 // exhaust underlying backend systems by allowing them to continue for too
 // long).
 //
+// Rejected requests never have their bodies read: the middleware responds
+// before the wrapped handler runs and closes the connection of requests that
+// carry a body, so clients cannot make the server receive large bodies of
+// requests it turned away.  Clients that sent "Expect: 100-continue" receive
+// the rejection without being asked to send their bodies at all.
+//
 // # Design Philosophy
 //
 // Allowing the client to specify the deadline (as a literal point in time) is
@@ -47,49 +84,579 @@ package httpdeadline
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
+)
+
+var (
+	// ErrEmptyValue indicates that the deadline source was present but empty.
+	ErrEmptyValue = errors.New("httpdeadline: empty deadline")
+	// ErrParse indicates that the deadline could not be parsed.
+	ErrParse = errors.New("httpdeadline: malformed deadline")
+	// ErrBudgetTooSmall indicates that the deadline leaves too little time to
+	// serve the request (see [WithMinimumServiceTime]).
+	ErrBudgetTooSmall = errors.New("httpdeadline: budget too small")
+	// ErrTooEarly indicates that the request arrived before the time its
+	// not-before header permits (see [WithNotBeforeHeader]).
+	ErrTooEarly = errors.New("httpdeadline: request too early")
+	// ErrMissingCapability indicates that the caller sent a deadline without
+	// the capability to have it honored (see [WithRequireCapability]).
+	ErrMissingCapability = errors.New("httpdeadline: caller may not set deadlines")
+	// ErrDeadlineExceeded is the [context.Cause] of request contexts whose
+	// client deadline passed.  It matches [context.DeadlineExceeded], but
+	// unlike it, distinguishes this package's deadline from other deadlines
+	// and from cancellation due to client disconnection.
+	ErrDeadlineExceeded = fmt.Errorf("httpdeadline: client deadline exceeded: %w", context.DeadlineExceeded)
 )
 
+type handler struct {
+	cfg    config
+	lookup func(*http.Request) (string, bool, error)
+	// resolve, if set, replaces lookup for sources whose values are parsed
+	// specially.  Its errors are already classified.
+	resolve       func(*config, *http.Request) (deadline time.Time, val string, ok bool, err error)
+	requireFuture bool // Reject expired deadlines regardless of config.
+	// statedBudget, if set, reports the relative budget sent alongside an
+	// absolute deadline, for WithCrossCheckTolerance.
+	statedBudget func(*http.Request) (time.Duration, bool)
+	next         http.Handler
+	source       Source
+	queryParam   string      // Set by FromQueryParams.
+	chain        []Extractor // Replaces lookup if nil; set by Policy.Handler.
+	provisional  bool        // Set by EarlyDeadline.
+	promote      bool        // Set by PromoteDeadline.
+	dryRun       bool        // Set by ValidateValue; decide leaves no trace.
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h = h.current()
+	rec := h.decide(req)
+	h.cfg.audit(req.Context(), rec)
+	switch rec.Outcome {
+	case OutcomeAbsent, OutcomeUnbounded:
+		if h.cfg.reportWriter {
+			w = &reportingWriter{ResponseWriter: w}
+		}
+		h.next.ServeHTTP(w, req)
+	case OutcomeRejected:
+		h.reject(w, req, rec)
+	default:
+		h.serveWithDeadline(w, req, rec)
+	}
+}
+
+// serveWithDeadline serves req under the deadline rec settled on.
+func (h *handler) serveWithDeadline(w http.ResponseWriter, req *http.Request, rec AuditRecord) {
+	var soft time.Time
+	if h.cfg.onSoftDeadline != nil {
+		soft = rec.Effective.Add(-h.cfg.softOffset)
+	}
+	a := &applied{
+		effective:   rec.Effective,
+		requested:   rec.Requested,
+		soft:        soft,
+		value:       rec.Value,
+		source:      rec.Source,
+		outcome:     rec.Outcome,
+		provisional: h.provisional && rec.Outcome != OutcomeDefault,
+		uncancelled: h.cfg.advisory || h.cfg.streaming,
+		trailer:     h.cfg.deadlineTrailer,
+	}
+	ctx := withApplied(req.Context(), a)
+	switch {
+	case h.cfg.advisory:
+		// The deadline is only reported.
+	case h.cfg.streaming:
+		if fn := h.cfg.atDeadline; fn != nil {
+			req := req.WithContext(ctx)
+			timer := time.AfterFunc(time.Until(rec.Effective), func() { fn(req) })
+			defer timer.Stop()
+		}
+	default:
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadlineCause(ctx, rec.Effective, ErrDeadlineExceeded)
+		defer cancel()
+	}
+	req = req.WithContext(ctx)
+	if fn := h.cfg.onDeadlineFired; fn != nil && !h.cfg.streaming {
+		stop := context.AfterFunc(ctx, func() {
+			if context.Cause(ctx) == ErrDeadlineExceeded {
+				fn(req)
+			}
+		})
+		defer stop()
+	}
+	if rec.deprecated {
+		warnDeprecated(w)
+	}
+	if name := h.cfg.announceDefault; name != "" && rec.Outcome == OutcomeDefault {
+		w.Header().Set(name, h.cfg.format(rec.Effective))
+	}
+	if fn := h.cfg.onSoftDeadline; fn != nil {
+		timer := time.AfterFunc(time.Until(soft), func() { fn(req) })
+		defer timer.Stop()
+	}
+	if h.cfg.multipartDeadline && !h.cfg.advisory && isMultipart(req) {
+		boundBody(w, req, rec.Effective)
+	}
+	if fn := h.cfg.sloObserver; fn != nil {
+		defer func() {
+			over := time.Since(rec.Effective)
+			fn(req.Pattern, over <= 0, max(over, 0))
+		}()
+	}
+	if fn := h.cfg.utilizationObserver; fn != nil {
+		start, budget := time.Now(), rec.Effective.Sub(rec.Time)
+		defer func() { fn(req, utilization(time.Since(start), budget)) }()
+	}
+	if h.cfg.grpcWebStatus || h.cfg.onFirstWrite != nil || h.cfg.deadlineTrailer != "" {
+		tw := &writeTracker{ResponseWriter: w}
+		if fn := h.cfg.onFirstWrite; fn != nil {
+			start, budget := time.Now(), rec.Effective.Sub(rec.Time)
+			tw.onFirstWrite = append(tw.onFirstWrite, func() { fn(req, time.Since(start), budget) })
+		}
+		if h.cfg.deadlineTrailer != "" {
+			tw.onFirstWrite = append(tw.onFirstWrite, func() { a.declareTrailer(tw.Header()) })
+			defer h.cfg.setDeadlineTrailer(tw, a)
+		}
+		if h.cfg.grpcWebStatus && !h.cfg.advisory {
+			defer writeGRPCWebDeadline(tw, req, rec.Effective)
+		}
+		w = tw
+	}
+	if h.cfg.flushDeadline && !h.cfg.advisory {
+		w = &flushWriter{ResponseWriter: w, deadline: rec.Effective}
+	}
+	if h.cfg.inFlight != nil {
+		defer h.cfg.inFlight.track(rec.Effective.Sub(rec.Time))()
+	}
+	if h.cfg.onTightBudget != nil && rec.Effective.Sub(rec.Time) < h.cfg.tightThreshold {
+		h.cfg.onTightBudget(req)
+	}
+	if h.cfg.reportWriter {
+		w = &reportingWriter{ResponseWriter: w, deadline: rec.Effective, ok: true}
+	}
+	if h.cfg.scheduler != nil {
+		h.schedule(w, req, rec.Effective)
+		return
+	}
+	h.next.ServeHTTP(w, req)
+}
+
+// decide determines what to do with the request's deadline without acting on
+// it.
+func (h *handler) decide(req *http.Request) AuditRecord {
+	rec := AuditRecord{
+		Time:   h.cfg.now(),
+		Method: req.Method,
+		Path:   req.URL.Path,
+	}
+	if name := h.cfg.correlationHeader; name != "" {
+		rec.CorrelationID = req.Header.Get(name)
+	}
+	if !h.cfg.allowsContentType(req) || h.cfg.bypasses(req) {
+		rec.Outcome = OutcomeAbsent
+		return rec
+	}
+	if d := h.cfg.tunnelDeadline; d > 0 && req.Method == http.MethodConnect {
+		rec.Effective, rec.Outcome = rec.Time.Add(d), OutcomeDefault
+		return rec
+	}
+	if name := h.cfg.notBeforeHeader; name != "" {
+		if val := req.Header.Get(name); val != "" {
+			notBefore, _, err := h.cfg.parse(val, SourceHeader)
+			if err != nil {
+				return rec.rejected(fmt.Errorf("%w: not-before: %v", ErrParse, err))
+			}
+			if rec.Time.Before(notBefore) {
+				return rec.rejected(fmt.Errorf("%w: not before %v", ErrTooEarly, notBefore))
+			}
+		}
+	}
+	var client string
+	if l := h.cfg.expiryLimiter; l != nil {
+		if client = l.key(req); l.limited(client, rec.Time) {
+			return rec.rejected(ErrRateLimited)
+		}
+	}
+	var (
+		val      string
+		src      = h.source
+		ok       bool
+		err      error
+		deadline time.Time // Set if resolve parsed val.
+	)
+	if h.resolve != nil {
+		deadline, val, ok, err = h.resolve(&h.cfg, req)
+	} else if val, src, ok, err = h.lookupValue(req); err != nil {
+		err = fmt.Errorf("%w: %v", ErrParse, err)
+	}
+	rec.Value = val
+	if ok {
+		rec.Source = src
+	}
+	if err != nil {
+		return rec.rejected(err)
+	}
+	if ok && h.resolve == nil && h.cfg.baggageMember != "" {
+		if val, ok, err = h.cfg.fromBaggage(val); err != nil {
+			return rec.rejected(fmt.Errorf("%w: %v", ErrParse, err))
+		}
+	}
+	if ok && h.cfg.capable != nil && !h.cfg.capable(req) {
+		return rec.rejected(ErrMissingCapability)
+	}
+	trusted := h.cfg.trusted == nil || h.cfg.trusted(req)
+	if ok && !trusted {
+		ok = false // Untrusted callers' values are ignored.
+	}
+	if ok && h.cfg.unboundedSentinel != "" && val == h.cfg.unboundedSentinel {
+		rec.Outcome = OutcomeUnbounded
+		return rec
+	}
+	if ok && h.cfg.relative == nil && h.cfg.clockHealthy != nil && !h.cfg.clockHealthy() {
+		// Absolute deadlines rely on clock agreement, so only relative budgets
+		// remain safe.
+		if budget, relative := h.stated(req); relative {
+			deadline = h.cfg.anchor(req, rec.Time).Add(budget)
+		} else {
+			ok = false
+		}
+	}
+	if !ok {
+		rec.Outcome = OutcomeAbsent
+		if !(h.cfg.defaultUntrustedOnly && trusted) {
+			if d := h.cfg.defaultFor(req); d > 0 {
+				rec.Effective, rec.Outcome = rec.Time.Add(d), OutcomeDefault
+			}
+		}
+		return rec
+	}
+	if val == "" {
+		return rec.rejected(ErrEmptyValue)
+	}
+	var layout string
+	switch rf := h.cfg.relative; {
+	case h.resolve != nil:
+		// Already parsed.
+	case rf != nil:
+		budget, err := rf.parse(val)
+		if err != nil {
+			return rec.rejected(err)
+		}
+		deadline, layout = h.cfg.anchor(req, rec.Time).Add(budget), rf.name
+	default:
+		if deadline, layout, err = h.cfg.parse(val, src); err != nil {
+			return rec.rejected(fmt.Errorf("%w: %v", ErrParse, err))
+		}
+	}
+	if h.cfg.formatMetrics && layout != "" && !h.dryRun {
+		formatHits().Add(layoutName(layout), 1)
+	}
+	rec.deprecated = h.resolve == nil && h.cfg.deprecated(src, layout)
+	rec.Requested, rec.Effective, rec.Outcome = deadline, deadline, OutcomeApplied
+	if (h.cfg.rejectExpired || h.requireFuture) && !deadline.After(rec.Time) {
+		if l := h.cfg.expiryLimiter; l != nil && !h.dryRun {
+			l.record(client, rec.Time)
+		}
+		return rec.rejected(fmt.Errorf("%w: %v", ErrDeadlineExpired, deadline))
+	}
+	if tolerance := h.cfg.crossCheckTolerance; tolerance > 0 {
+		if budget, ok := h.stated(req); ok && deadline.Sub(rec.Time) > budget+tolerance {
+			// The client's clock likely runs fast; trust the relative budget.
+			rec.Effective, rec.Outcome = h.cfg.anchor(req, rec.Time).Add(budget), OutcomeClamped
+		}
+	}
+	if factor := h.cfg.scale; factor != 0 {
+		if budget := rec.Effective.Sub(rec.Time); budget > 0 {
+			scaled := scaleBudget(budget, factor)
+			rec.Effective = rec.Time.Add(scaled)
+			if scaled < budget {
+				rec.Outcome = OutcomeClamped
+			}
+		}
+	}
+	if h.cfg.maxDeadline != nil {
+		if d := h.cfg.maxDeadline(req); d > 0 {
+			if limit := rec.Time.Add(d); rec.Effective.After(limit) {
+				rec.Effective, rec.Outcome = limit, OutcomeClamped
+			}
+		}
+	}
+	if h.cfg.adaptiveCap != nil {
+		if d := h.cfg.adaptiveCap(); d > 0 {
+			if limit := rec.Time.Add(d); rec.Effective.After(limit) {
+				rec.Effective, rec.Outcome = limit, OutcomeClamped
+			}
+		}
+	}
+	if h.promote {
+		// Promotion never extends the provisional deadline.
+		if a, ok := appliedFrom(req.Context()); ok && rec.Effective.After(a.effective) {
+			rec.Effective, rec.Outcome = a.effective, OutcomeClamped
+		}
+	}
+	if tiers := h.cfg.budgetLadder; tiers != nil {
+		budget := rec.Effective.Sub(rec.Time)
+		tier, ok := snapBudget(budget, tiers)
+		if !ok {
+			return rec.rejected(fmt.Errorf("%w: budget of %v is under the smallest tier of %v", ErrBudgetTooSmall, budget, tiers[0]))
+		}
+		if tier < budget {
+			rec.Effective, rec.Outcome = rec.Time.Add(tier), OutcomeClamped
+		}
+	}
+	var drawn time.Duration // From budgetBucket.
+	if b := h.cfg.budgetBucket; b != nil {
+		if budget := rec.Effective.Sub(rec.Time); budget > 0 {
+			var granted time.Duration
+			if h.dryRun {
+				granted = b.peek(rec.Time, budget)
+			} else {
+				granted = b.take(rec.Time, budget)
+				drawn = granted
+			}
+			if granted <= 0 {
+				return rec.rejected(ErrBudgetExhausted)
+			}
+			if granted < budget {
+				rec.Effective, rec.Outcome = rec.Time.Add(granted), OutcomeClamped
+			}
+		}
+	}
+	// Requests rejected from here on return what they drew.
+	reject := func(err error) AuditRecord {
+		if drawn > 0 {
+			h.cfg.budgetBucket.refund(drawn)
+		}
+		return rec.rejected(err)
+	}
+	if h.cfg.minServiceTime != nil {
+		if need, budget := h.cfg.minServiceTime(req), rec.Effective.Sub(rec.Time); need > 0 && budget < need {
+			return reject(fmt.Errorf("%w: budget of %v is under the minimum service time of %v", ErrBudgetTooSmall, budget, need))
+		}
+	}
+	if need := h.cfg.expectContinueMin; need > 0 && expectsContinue(req) {
+		if budget := rec.Effective.Sub(rec.Time); budget < need {
+			return reject(fmt.Errorf("%w: budget of %v is under the minimum of %v for 100-continue", ErrBudgetTooSmall, budget, need))
+		}
+	}
+	return rec
+}
+
+// stated reports the relative budget sent alongside the request's absolute
+// deadline, if any.
+func (h *handler) stated(req *http.Request) (time.Duration, bool) {
+	if h.statedBudget == nil {
+		return 0, false
+	}
+	return h.statedBudget(req)
+}
+
+// reject is the single place where requests are turned away.  The response
+// body explains why.
+func (h *handler) reject(w http.ResponseWriter, req *http.Request, rec AuditRecord) {
+	if hasBody(req) {
+		// The body goes unread.  Close the connection rather than let the
+		// server drain a body of unbounded size to reuse it.
+		w.Header().Set("Connection", "close")
+	}
+	if h.cfg.reasonMetrics {
+		rejections().Add(reasonName(rec.Err), 1)
+	}
+	if h.cfg.problemJSON {
+		writeProblem(w, rec, h.cfg.statusOf(rec.Err))
+		return
+	}
+	http.Error(w, rec.Err.Error(), h.cfg.statusOf(rec.Err))
+}
+
+// hasBody reports whether req may carry a request body.
+func hasBody(req *http.Request) bool {
+	return req.Body != nil && req.Body != http.NoBody && req.ContentLength != 0
+}
+
+// statusOf maps a rejection reason to its HTTP status code.
+func (c *config) statusOf(err error) int {
+	switch {
+	case errors.Is(err, ErrMissingCapability):
+		if c.capabilityStatus != 0 {
+			return c.capabilityStatus
+		}
+		return http.StatusForbidden
+	case errors.Is(err, ErrTooEarly):
+		return http.StatusTooEarly
+	case errors.Is(err, ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrBudgetExhausted):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// From wraps the provided [http.Handler] in an outer http.Handler that sets a
+// maximum deadline on the [http.Request]'s context from the raw value that
+// extract finds in the request.  It is the general form of [FromHeader] and
+// [FromQueryParams]: extract locates the value, and the middleware parses,
+// validates, and applies it per opts.
+//
+// extract reports false if the request carries no deadline, in which case the
+// request passes through (subject to defaults like [WithDefaultDeadline]).  An
+// error from extract means that the deadline is malformed; the request is
+// rejected with [http.StatusBadRequest] and an error matching [ErrParse].  See
+// [FromTime] for extractors that parse the deadline themselves.
+func From(extract func(*http.Request) (string, bool, error), h http.Handler, opts ...Option) http.Handler {
+	return &handler{
+		cfg:    mustConfig(opts),
+		lookup: extract,
+		next:   h,
+	}
+}
+
+// FromTime is like [From], but extract returns an already-parsed deadline,
+// which suits values that need decoding beyond what layouts express (e.g., a
+// field of a structured envelope header).  Layout options do not apply, but
+// the rest of the policy, like caps, defaults, and rejection, applies as
+// usual.  extract reporting a zero time is rejected like an empty value.
+// [AuditRecord.Value] records the deadline in [time.RFC3339Nano].
+func FromTime(extract func(*http.Request) (time.Time, bool, error), h http.Handler, opts ...Option) http.Handler {
+	return &handler{
+		cfg: mustConfig(opts),
+		resolve: func(_ *config, req *http.Request) (time.Time, string, bool, error) {
+			t, ok, err := extract(req)
+			switch {
+			case err != nil:
+				return time.Time{}, "", true, fmt.Errorf("%w: %v", ErrParse, err)
+			case !ok:
+				return time.Time{}, "", false, nil
+			case t.IsZero():
+				return time.Time{}, "", true, ErrEmptyValue
+			}
+			return t, t.Format(time.RFC3339Nano), true, nil
+		},
+		next: h,
+	}
+}
+
+// FromContextValue wraps the provided [http.Handler] in an outer http.Handler
+// that sets a maximum deadline on the [http.Request]'s context from the
+// [time.Time] that other in-process middleware stored in that context under
+// key, which bridges entry points other than HTTP (e.g., a message queue
+// consumer that adapts jobs into requests) to the same policy.  Requests
+// whose context holds no value or the zero time under key pass through
+// (subject to defaults like [WithDefaultDeadline]); those holding a value of
+// another type are rejected with [http.StatusBadRequest] and an error
+// matching [ErrParse].  It otherwise behaves like [FromTime].
+func FromContextValue(key any, h http.Handler, opts ...Option) http.Handler {
+	return FromTime(func(req *http.Request) (time.Time, bool, error) {
+		switch v := req.Context().Value(key).(type) {
+		case nil:
+			return time.Time{}, false, nil
+		case time.Time:
+			return v, !v.IsZero(), nil
+		default:
+			return time.Time{}, false, fmt.Errorf("context value of type %T, not time.Time", v)
+		}
+	}, h, opts...)
+}
+
 // FromHeader wraps the provided [http.Handler] in an outer http.Handler that
 // sets a maximum a deadline on the [http.Request]'s context if the named HTTP
 // header is set to a [http.ParseTime]-compatible value.  That value becomes the
 // maximum deadline for the request.
-func FromHeader(name string, h http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+func FromHeader(name string, h http.Handler, opts ...Option) http.Handler {
+	hh := From(func(req *http.Request) (string, bool, error) {
 		if _, ok := req.Header[http.CanonicalHeaderKey(name)]; !ok {
-			h.ServeHTTP(w, req)
-			return
-		}
-		val := req.Header.Get(name)
-		time, err := http.ParseTime(val)
-		if val == "" || err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			return
+			return "", false, nil
 		}
-		ctx, cancel := context.WithDeadline(req.Context(), time)
-		defer cancel()
-		h.ServeHTTP(w, req.WithContext(ctx))
-	})
+		return req.Header.Get(name), true, nil
+	}, h, opts...).(*handler)
+	hh.source = SourceHeader
+	return hh
 }
 
 // FromQueryParams wraps the provided [http.Handler] in an outer http.Handler
 // that sets a maximum a deadline on the [http.Request]'s context if the named
 // query parameter is set to a [http.ParseTime]-compatible value.  That value
-// becomes the maximum deadline for the request.
-func FromQueryParams(name string, h http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		if !req.URL.Query().Has(name) {
-			h.ServeHTTP(w, req)
-			return
-		}
-		val := req.URL.Query().Get(name)
-		time, err := http.ParseTime(val)
-		if val == "" || err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		ctx, cancel := context.WithDeadline(req.Context(), time)
-		defer cancel()
-		h.ServeHTTP(w, req.WithContext(ctx))
-	})
+// becomes the maximum deadline for the request.  See [ApplyFromValues] for
+// handlers that have already parsed the query.
+func FromQueryParams(name string, h http.Handler, opts ...Option) http.Handler {
+	hh := From(func(req *http.Request) (string, bool, error) {
+		return lookupValues(req.URL.Query(), name)
+	}, h, opts...).(*handler)
+	hh.source, hh.queryParam = SourceQuery, name
+	return hh
+}
+
+// FromPathValue wraps the provided [http.Handler] in an outer http.Handler that
+// sets a maximum deadline on the [http.Request]'s context from the named path
+// wildcard that [http.ServeMux] matched (see [http.Request.PathValue]), as in
+// the pattern "/v1/report/deadline/{deadline}/run".  Requests without the
+// wildcard or with an empty value pass through.
+//
+// The wrapped handler must be registered with the ServeMux directly, as path
+// values are only populated on requests it has routed.
+func FromPathValue(name string, h http.Handler, opts ...Option) http.Handler {
+	hh := From(func(req *http.Request) (string, bool, error) {
+		val := req.PathValue(name)
+		return val, val != "", nil
+	}, h, opts...).(*handler)
+	hh.source = SourcePath
+	return hh
+}
+
+// FromStartAndOffset wraps the provided [http.Handler] in an outer http.Handler
+// that sets a maximum deadline on the [http.Request]'s context from a pair of
+// HTTP headers: startHeader holds an absolute start time in any accepted
+// format, and offsetHeader holds a budget in the grammar of
+// [time.ParseDuration] (e.g., "30s").  The deadline is their sum.  This suits
+// edge proxies that stamp when they received a request alongside the budget
+// granted to it, sidestepping the ambiguity of bare durations.
+//
+// The headers must come as a pair: requests with neither pass through (subject
+// to defaults like [WithDefaultDeadline]), but requests with only one are
+// rejected with [http.StatusBadRequest] and an error matching [ErrParse].
+// Offsets are classified like [WithDurationValues] values, and deadlines that
+// have already passed are rejected with an error matching
+// [ErrDeadlineExpired].  Caps like [WithMaxDeadline] apply as usual, as
+// does [WithCrossCheckTolerance].  [AuditRecord.Value] records the pair as
+// "start + offset".
+func FromStartAndOffset(startHeader, offsetHeader string, h http.Handler, opts ...Option) http.Handler {
+	return &handler{
+		cfg: mustConfig(opts),
+		resolve: func(c *config, req *http.Request) (time.Time, string, bool, error) {
+			_, hasStart := req.Header[http.CanonicalHeaderKey(startHeader)]
+			_, hasOffset := req.Header[http.CanonicalHeaderKey(offsetHeader)]
+			if !hasStart && !hasOffset {
+				return time.Time{}, "", false, nil
+			}
+			start, offset := req.Header.Get(startHeader), req.Header.Get(offsetHeader)
+			val := start + " + " + offset
+			switch {
+			case !hasStart || !hasOffset:
+				return time.Time{}, val, true, fmt.Errorf("%w: %s and %s must be sent together", ErrParse, startHeader, offsetHeader)
+			case start == "" || offset == "":
+				return time.Time{}, val, true, ErrEmptyValue
+			}
+			t, _, err := c.parse(start, SourceHeader)
+			if err != nil {
+				return time.Time{}, val, true, fmt.Errorf("%w: start: %v", ErrParse, err)
+			}
+			budget, err := parseDurationBudget(offset)
+			if err != nil {
+				return time.Time{}, val, true, err
+			}
+			return t.Add(budget), val, true, nil
+		},
+		requireFuture: true,
+		statedBudget: func(req *http.Request) (time.Duration, bool) {
+			budget, err := parseDurationBudget(req.Header.Get(offsetHeader))
+			return budget, err == nil
+		},
+		next:   h,
+		source: SourceHeader,
+	}
 }
@@ -1,6 +1,10 @@
 package httpdeadline
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -54,6 +58,8 @@ func asTimeFormat(t time.Time) string { return t.Format(http.TimeFormat) }
 func asRFC850(t time.Time) string     { return t.Format(time.RFC850) }
 func asANSIC(t time.Time) string      { return t.Format(time.ANSIC) }
 
+func ptr[T any](v T) *T { return &v }
+
 func TestFromHeader(t *testing.T) {
 	for _, test := range []struct {
 		Name string
@@ -300,3 +306,471 @@ func TestFromQueryParams(t *testing.T) {
 		})
 	}
 }
+
+func TestWithMaxDeadline(t *testing.T) {
+	const limit = time.Minute
+	for _, test := range []struct {
+		Name string
+
+		Max      time.Duration
+		Deadline time.Time
+		Clamped  bool
+	}{
+		{
+			Name:     "past",
+			Max:      limit,
+			Deadline: now,
+			Clamped:  false,
+		},
+		{
+			Name:     "beyond-cap",
+			Max:      limit,
+			Deadline: time.Now().Add(time.Hour).Truncate(time.Second),
+			Clamped:  true,
+		},
+		{
+			Name:     "disabled",
+			Max:      0,
+			Deadline: time.Now().Add(time.Hour).Truncate(time.Second),
+			Clamped:  false,
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var spy spyHandler
+			h := FromHeader("X-MTP-Deadline", &spy, WithMaxDeadline(test.Max))
+			srv := newServer(t, h)
+			req := newGetRequest(t, urlOf(t, srv))
+			req.Header.Set("X-MTP-Deadline", asTimeFormat(test.Deadline))
+			before := time.Now()
+			resp, err := newClient().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			after := time.Now()
+			if got, want := resp.StatusCode, 200; got != want {
+				t.Errorf("resp.StatusCode = %v, want %v", got, want)
+			}
+			if !spy.OK {
+				t.Fatal("spy.OK = false, want true")
+			}
+			if !test.Clamped {
+				if got, want := spy.Deadline, test.Deadline; !got.Equal(want) {
+					t.Errorf("spy.Deadline = %v, want %v", got, want)
+				}
+				return
+			}
+			if got := spy.Deadline; got.Before(before.Add(test.Max)) || got.After(after.Add(test.Max)) {
+				t.Errorf("spy.Deadline = %v, want within [%v, %v]", got, before.Add(test.Max), after.Add(test.Max))
+			}
+		})
+	}
+}
+
+func TestFrom(t *testing.T) {
+	// extract takes the deadline from a "deadline" cookie.
+	extract := func(req *http.Request) (string, bool, error) {
+		cookie, err := req.Cookie("deadline")
+		if errors.Is(err, http.ErrNoCookie) {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		val, err := url.QueryUnescape(cookie.Value)
+		return val, true, err
+	}
+	for _, test := range []struct {
+		Name string
+
+		Cookie string
+
+		Status   int
+		Deadline time.Time
+		OK       bool
+	}{
+		{Name: "none", Status: 200},
+		{Name: "valid", Cookie: url.QueryEscape(asTimeFormat(now)), Status: 200, Deadline: now, OK: true},
+		{Name: "invalid", Cookie: "garbage", Status: 400},
+		{Name: "extract-error", Cookie: "%zz", Status: 400},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var spy spyHandler
+			h := From(extract, &spy)
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.Cookie != "" {
+				req.Header.Set("Cookie", "deadline="+test.Cookie)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, test.Status; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+			if got, want := spy.Deadline, test.Deadline; !got.Equal(want) {
+				t.Errorf("spy.Deadline = %v, want %v", got, want)
+			}
+			if got, want := spy.OK, test.OK; got != want {
+				t.Errorf("spy.OK = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// requestContext models an envelope header that packs several fields,
+// including the deadline, into base64-encoded JSON.
+type requestContext struct {
+	TraceID  string `json:"trace_id"`
+	Deadline string `json:"deadline"`
+}
+
+func encodeRequestContext(rc requestContext) string {
+	b, _ := json.Marshal(rc)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func decodeRequestContext(req *http.Request) (rc requestContext, ok bool, err error) {
+	val := req.Header.Get("X-Request-Context")
+	if val == "" {
+		return rc, false, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(val)
+	if err != nil {
+		return rc, false, err
+	}
+	return rc, true, json.Unmarshal(b, &rc)
+}
+
+func TestComposite(t *testing.T) {
+	fromString := func(h http.Handler, opts ...Option) http.Handler {
+		return From(func(req *http.Request) (string, bool, error) {
+			rc, ok, err := decodeRequestContext(req)
+			return rc.Deadline, ok, err
+		}, h, opts...)
+	}
+	fromTime := func(h http.Handler, opts ...Option) http.Handler {
+		return FromTime(func(req *http.Request) (time.Time, bool, error) {
+			rc, ok, err := decodeRequestContext(req)
+			if !ok || err != nil {
+				return time.Time{}, ok, err
+			}
+			t, err := time.Parse(time.RFC3339, rc.Deadline)
+			return t, true, err
+		}, h, opts...)
+	}
+	for _, from := range []struct {
+		Name string
+		New  func(http.Handler, ...Option) http.Handler
+	}{
+		{Name: "string", New: fromString},
+		{Name: "time", New: fromTime},
+	} {
+		for _, test := range []struct {
+			Name string
+
+			Header string
+
+			Status   int
+			Deadline time.Time
+			OK       bool
+			Outcome  Outcome
+		}{
+			{Name: "absent", Status: 200, Outcome: OutcomeAbsent},
+			{Name: "applied", Header: encodeRequestContext(requestContext{TraceID: "abc", Deadline: now.Add(time.Minute).Format(time.RFC3339)}), Status: 200, Deadline: now.Add(time.Minute), OK: true, Outcome: OutcomeApplied},
+			{Name: "clamped", Header: encodeRequestContext(requestContext{TraceID: "abc", Deadline: now.Add(time.Hour).Format(time.RFC3339)}), Status: 200, Deadline: now.Add(2 * time.Minute), OK: true, Outcome: OutcomeClamped},
+			{Name: "empty", Header: encodeRequestContext(requestContext{TraceID: "abc"}), Status: 400, Outcome: OutcomeRejected},
+			{Name: "malformed-envelope", Header: "!!", Status: 400, Outcome: OutcomeRejected},
+			{Name: "malformed-deadline", Header: encodeRequestContext(requestContext{Deadline: "soon"}), Status: 400, Outcome: OutcomeRejected},
+		} {
+			t.Run(from.Name+"/"+test.Name, func(t *testing.T) {
+				var (
+					spy spyHandler
+					got AuditRecord
+				)
+				h := from.New(&spy,
+					WithClock(func() time.Time { return now }),
+					WithLayout(time.RFC3339),
+					WithMaxDeadline(2*time.Minute),
+					WithAuditSink(func(rec AuditRecord) { got = rec }))
+				req := httptest.NewRequest("GET", "/", nil)
+				if test.Header != "" {
+					req.Header.Set("X-Request-Context", test.Header)
+				}
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, req)
+				if got, want := rec.Code, test.Status; got != want {
+					t.Errorf("rec.Code = %v, want %v", got, want)
+				}
+				if got, want := spy.Deadline, test.Deadline; !got.Equal(want) {
+					t.Errorf("spy.Deadline = %v, want %v", got, want)
+				}
+				if got, want := spy.OK, test.OK; got != want {
+					t.Errorf("spy.OK = %v, want %v", got, want)
+				}
+				if got, want := got.Outcome, test.Outcome; got != want {
+					t.Errorf("rec.Outcome = %v, want %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestFromContextValue(t *testing.T) {
+	type jobDeadlineKey struct{}
+	for _, test := range []struct {
+		Name  string
+		Value any
+
+		Status   int
+		Deadline time.Time
+		OK       bool
+	}{
+		{Name: "missing", Status: 200},
+		{Name: "zero", Value: time.Time{}, Status: 200},
+		{Name: "applied", Value: now.Add(time.Minute), Status: 200, Deadline: now.Add(time.Minute), OK: true},
+		{Name: "clamped", Value: now.Add(time.Hour), Status: 200, Deadline: now.Add(2 * time.Minute), OK: true},
+		{Name: "wrong-type", Value: "soon", Status: 400},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var spy spyHandler
+			h := FromContextValue(jobDeadlineKey{}, &spy,
+				WithClock(func() time.Time { return now }),
+				WithMaxDeadline(2*time.Minute))
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.Value != nil {
+				req = req.WithContext(context.WithValue(req.Context(), jobDeadlineKey{}, test.Value))
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, test.Status; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+			if got, want := spy.Deadline, test.Deadline; !got.Equal(want) {
+				t.Errorf("spy.Deadline = %v, want %v", got, want)
+			}
+			if got, want := spy.OK, test.OK; got != want {
+				t.Errorf("spy.OK = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestFromPathValue(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+
+		Path string
+
+		Status   int
+		Deadline time.Time
+		OK       bool
+	}{
+		{Name: "valid", Path: "/v1/report/deadline/" + now.Format(time.RFC3339) + "/run", Status: 200, Deadline: now, OK: true},
+		{Name: "escaped", Path: "/v1/report/deadline/" + url.PathEscape(asTimeFormat(now)) + "/run", Status: 200, Deadline: now, OK: true},
+		{Name: "invalid", Path: "/v1/report/deadline/garbage/run", Status: 400},
+		{Name: "other-route", Path: "/v1/report/run", Status: 200},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var spy spyHandler
+			h := FromPathValue("deadline", &spy, WithLayout(time.RFC3339))
+			mux := http.NewServeMux()
+			mux.Handle("/v1/report/deadline/{deadline}/run", h)
+			mux.Handle("/v1/report/run", h)
+			srv := newServer(t, mux)
+			resp, err := newClient().Get(srv.URL + test.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got, want := resp.StatusCode, test.Status; got != want {
+				t.Errorf("resp.StatusCode = %v, want %v", got, want)
+			}
+			if got, want := spy.Deadline, test.Deadline; !got.Equal(want) {
+				t.Errorf("spy.Deadline = %v, want %v", got, want)
+			}
+			if got, want := spy.OK, test.OK; got != want {
+				t.Errorf("spy.OK = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// discardWriter is an http.ResponseWriter that discards everything, so that
+// benchmarks measure the middleware rather than response recording.
+type discardWriter struct{ h http.Header }
+
+func (w *discardWriter) Header() http.Header {
+	if w.h == nil {
+		w.h = make(http.Header)
+	}
+	return w.h
+}
+func (w *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+func benchmarkSource(b *testing.B, newHandler func(http.Handler) http.Handler, set func(*http.Request, string)) {
+	noop := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	valid := asTimeFormat(time.Now().Add(time.Hour))
+	for _, bench := range []struct {
+		Name    string
+		Handler http.Handler
+		Value   *string
+	}{
+		{Name: "pass-through", Handler: noop, Value: &valid},
+		{Name: "present-valid", Handler: newHandler(noop), Value: &valid},
+		{Name: "present-invalid", Handler: newHandler(noop), Value: ptr("garbage")},
+		{Name: "absent", Handler: newHandler(noop)},
+	} {
+		b.Run(bench.Name, func(b *testing.B) {
+			req := httptest.NewRequest("GET", "/teapotz?q=earl+grey", nil)
+			if bench.Value != nil {
+				set(req, *bench.Value)
+			}
+			var w discardWriter
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				bench.Handler.ServeHTTP(&w, req)
+			}
+		})
+	}
+}
+
+func BenchmarkFromHeader(b *testing.B) {
+	benchmarkSource(b, func(h http.Handler) http.Handler {
+		return FromHeader("X-MTP-Deadline", h)
+	}, func(req *http.Request, val string) {
+		req.Header.Set("X-MTP-Deadline", val)
+	})
+}
+
+func BenchmarkFromQueryParams(b *testing.B) {
+	benchmarkSource(b, func(h http.Handler) http.Handler {
+		return FromQueryParams("deadline", h)
+	}, func(req *http.Request, val string) {
+		req.URL.RawQuery += "&deadline=" + url.QueryEscape(val)
+	})
+}
+
+func TestProtocolAgnostic(t *testing.T) {
+	deadline := time.Now().Add(time.Hour).Truncate(time.Second)
+	type observation struct {
+		proto    string
+		deadline time.Time
+		ok       bool
+	}
+	observe := func(t *testing.T, do func(http.Handler)) observation {
+		t.Helper()
+		var got observation
+		do(FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			got.proto = req.Proto
+			got.deadline, got.ok = Deadline(req.Context())
+		})))
+		return got
+	}
+	overServer := func(h2 bool) func(http.Handler) {
+		return func(h http.Handler) {
+			srv := httptest.NewUnstartedServer(h)
+			srv.EnableHTTP2 = h2
+			srv.StartTLS()
+			t.Cleanup(srv.Close)
+			req := newGetRequest(t, urlOf(t, srv))
+			req.Header.Set("X-MTP-Deadline", asTimeFormat(deadline))
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+		}
+	}
+	for _, test := range []struct {
+		Name  string
+		Do    func(http.Handler)
+		Proto string
+	}{
+		{Name: "http1", Do: overServer(false), Proto: "HTTP/1.1"},
+		{Name: "http2", Do: overServer(true), Proto: "HTTP/2.0"},
+		{
+			// No HTTP/3 server is available in the standard library, so
+			// simulate one handing a request to its http.Handler.
+			Name: "http3",
+			Do: func(h http.Handler) {
+				req := httptest.NewRequest("GET", "https://example.com/", nil)
+				req.Proto, req.ProtoMajor, req.ProtoMinor = "HTTP/3.0", 3, 0
+				req.Header.Set("X-MTP-Deadline", asTimeFormat(deadline))
+				h.ServeHTTP(httptest.NewRecorder(), req)
+			},
+			Proto: "HTTP/3.0",
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			got := observe(t, test.Do)
+			if got.proto != test.Proto {
+				t.Errorf("req.Proto = %q, want %q", got.proto, test.Proto)
+			}
+			if !got.ok || !got.deadline.Equal(deadline) {
+				t.Errorf("Deadline(ctx) = %v, %v; want %v, true", got.deadline, got.ok, deadline)
+			}
+		})
+	}
+}
+
+func TestTimeoutHandlerInteraction(t *testing.T) {
+	const (
+		short = 50 * time.Millisecond
+		long  = 150 * time.Millisecond
+	)
+	// waiter waits for its context to end and returns without writing, like a
+	// handler whose work was cancelled.
+	type observation struct {
+		budget time.Duration // Of the context the handler sees.
+	}
+	observations := make(chan observation, 1)
+	waiter := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		deadline, _ := req.Context().Deadline()
+		start := time.Now()
+		<-req.Context().Done()
+		observations <- observation{budget: deadline.Sub(start)}
+	})
+	timeoutOutside := func(timeout time.Duration) http.Handler {
+		return http.TimeoutHandler(FromHeader("X-MTP-Deadline", waiter, WithLayout(time.RFC3339Nano)), timeout, "timeout")
+	}
+	timeoutInside := func(timeout time.Duration) http.Handler {
+		return FromHeader("X-MTP-Deadline", http.TimeoutHandler(waiter, timeout, "timeout"), WithLayout(time.RFC3339Nano))
+	}
+	for _, test := range []struct {
+		Name    string
+		Handler http.Handler
+		Client  time.Duration // Budget of the client's deadline.
+
+		Budget time.Duration // That the handler sees, roughly.
+		Status int
+	}{
+		// With TimeoutHandler outside, the client's deadline ending first
+		// returns whatever the handler wrote; TimeoutHandler answers 503 only
+		// when its own timeout ends first.
+		{Name: "outside/client-first", Handler: timeoutOutside(long), Client: short, Budget: short, Status: http.StatusOK},
+		{Name: "outside/timeout-first", Handler: timeoutOutside(short), Client: long, Budget: short, Status: http.StatusServiceUnavailable},
+		// With TimeoutHandler inside, it answers 503 whichever ends first, as
+		// it cannot tell the client's deadline from its own timeout.
+		{Name: "inside/client-first", Handler: timeoutInside(long), Client: short, Budget: short, Status: http.StatusServiceUnavailable},
+		{Name: "inside/timeout-first", Handler: timeoutInside(short), Client: long, Budget: short, Status: http.StatusServiceUnavailable},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			srv := newServer(t, test.Handler)
+			req := newGetRequest(t, urlOf(t, srv))
+			req.Header.Set("X-MTP-Deadline", time.Now().Add(test.Client).Format(time.RFC3339Nano))
+			resp, err := newClient().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got, want := resp.StatusCode, test.Status; got != want {
+				t.Errorf("resp.StatusCode = %v, want %v", got, want)
+			}
+			// The handler sees the earlier of the two deadlines.
+			got := <-observations
+			if got.budget > test.Budget || got.budget < test.Budget-40*time.Millisecond {
+				t.Errorf("handler's budget = %v, want about %v", got.budget, test.Budget)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package httpdeadline
+
+import (
+	"net/http"
+	"slices"
+)
+
+// deprecationWarning is the Warning header (RFC 7234, section 5.5) value sent
+// for deprecated deadline sources and formats.  Code 199 is the miscellaneous
+// warning.
+const deprecationWarning = `199 - "deprecated deadline source or format"`
+
+// A deprecation is a source and layout configured with
+// WithDeprecationWarning.
+type deprecation struct {
+	src    Source
+	layout string // Any if empty.
+}
+
+// WithDeprecationWarning helps migrate clients off a deadline source or
+// format: deadlines parsed from src in layout (or in any format if layout is
+// empty) are still honored, but the response carries the standard HTTP
+// Warning header
+//
+//	Warning: 199 - "deprecated deadline source or format"
+//
+// to signal the deprecation to clients and intermediaries.  layout names a
+// layout accepted per [WithLayout] or [WithSourceLayout], or "duration" or
+// "milliseconds" for [WithDurationValues] and [WithMillisecondValues].  It may
+// be given several times to deprecate several.  The header must be set before
+// the wrapped handler writes its response, so it is set before the handler
+// runs.
+func WithDeprecationWarning(src Source, layout string) Option {
+	if src < SourceHeader || src > SourceCookie {
+		return invalidf("unknown source %d", src)
+	}
+	return optionFunc(func(c *config) {
+		c.deprecations = append(slices.Clip(c.deprecations), deprecation{src, layout})
+	})
+}
+
+// deprecated reports whether values from src in layout are deprecated.
+func (c *config) deprecated(src Source, layout string) bool {
+	for _, d := range c.deprecations {
+		if d.src == src && (d.layout == "" || d.layout == layout) {
+			return true
+		}
+	}
+	return false
+}
+
+// warnDeprecated adds the deprecation warning to w's header.
+func warnDeprecated(w http.ResponseWriter) {
+	w.Header().Add("Warning", deprecationWarning)
+}
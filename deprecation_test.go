@@ -0,0 +1,53 @@
+package httpdeadline
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestWithDeprecationWarning(t *testing.T) {
+	deadline := time.Now().Add(time.Hour)
+	opts := []Option{
+		WithDeprecationWarning(SourceHeader, time.RFC850),
+		WithDeprecationWarning(SourceQuery, ""),
+	}
+	noop := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	fromHeader := FromHeader("X-MTP-Deadline", noop, opts...)
+	fromQuery := FromQueryParams("deadline", noop, opts...)
+	for _, test := range []struct {
+		Name    string
+		Handler http.Handler
+		Header  string
+		Query   string
+
+		Warning string
+	}{
+		{Name: "header-preferred", Handler: fromHeader, Header: asTimeFormat(deadline)},
+		{Name: "header-deprecated-format", Handler: fromHeader, Header: asRFC850(deadline), Warning: `199 - "deprecated deadline source or format"`},
+		{Name: "header-malformed", Handler: fromHeader, Header: "garbage"},
+		{Name: "query-deprecated-source", Handler: fromQuery, Query: asTimeFormat(deadline), Warning: `199 - "deprecated deadline source or format"`},
+		{Name: "query-absent", Handler: fromQuery},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.Header != "" {
+				req.Header.Set("X-MTP-Deadline", test.Header)
+			}
+			if test.Query != "" {
+				req.URL.RawQuery = url.Values{"deadline": {test.Query}}.Encode()
+			}
+			rec := httptest.NewRecorder()
+			test.Handler.ServeHTTP(rec, req)
+			if got, want := rec.Header().Get("Warning"), test.Warning; got != want {
+				t.Errorf("Warning = %q, want %q", got, want)
+			}
+		})
+	}
+	if _, err := NewPolicy(WithDeprecationWarning(Source(0), "")); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("NewPolicy(WithDeprecationWarning(Source(0), ...)) = %v, want %v", err, ErrInvalidOption)
+	}
+}
@@ -0,0 +1,105 @@
+package httpdeadline
+
+import (
+	"net/http"
+	"slices"
+	"time"
+)
+
+// A Description summarizes the effective settings of a [Policy].  It is a
+// copy; modifying it does not affect the Policy.
+type Description struct {
+	// MaxDeadline is the cap set by [WithMaxDeadline].  It is zero if there is
+	// no cap or if the cap is computed per request.
+	MaxDeadline time.Duration
+	// MaxDeadlineFunc reports whether the cap is computed per request (see
+	// [WithMaxDeadlineFunc]).
+	MaxDeadlineFunc bool
+	// AdaptiveCap reports whether [WithAdaptiveCap] is in effect.
+	AdaptiveCap bool
+	// DefaultDeadline is the default set by [WithDefaultDeadline].  It is zero
+	// if there is no default or if the default is computed per request.
+	DefaultDeadline time.Duration
+	// DefaultDeadlineFunc reports whether the default is computed per request
+	// (see [WithDefaultDeadlineFunc]).
+	DefaultDeadlineFunc bool
+	// Layouts are the accepted inbound layouts in the order they are tried
+	// (see [WithLayout]).
+	Layouts []string
+	// SourceLayouts are the additional layouts accepted from each source (see
+	// [WithSourceLayout]).
+	SourceLayouts map[Source][]string
+	// DurationValues and MillisecondValues report whether values are
+	// relative budgets instead (see [WithDurationValues] and
+	// [WithMillisecondValues]).
+	DurationValues, MillisecondValues bool
+	// EmitFormat is the layout of outbound deadlines (see [WithEmitFormat]).
+	EmitFormat string
+	// ContentTypes restricts the requests handled (see
+	// [WithContentTypeAllowlist]).  It is empty if all are handled.
+	ContentTypes []string
+	// CorrelationHeader, NotBeforeHeader, BaggageMember, and
+	// UnboundedSentinel are set by [WithCorrelationHeader],
+	// [WithNotBeforeHeader], [WithBaggageMember], and [WithUnboundedSentinel].
+	CorrelationHeader, NotBeforeHeader, BaggageMember, UnboundedSentinel string
+	// DeadlineScale is the factor set by [WithDeadlineScale], or 1.
+	DeadlineScale float64
+	// TrustedSource reports whether [WithTrustedSource] is in effect.
+	TrustedSource bool
+	// DefaultForUntrustedOnly reports whether
+	// [WithDefaultForUntrustedOnly] is in effect.
+	DefaultForUntrustedOnly bool
+	// Streaming reports whether [WithStreamingMode] is in effect.
+	Streaming bool
+	// AuditSinks counts the sinks registered with [WithAuditSink].
+	AuditSinks int
+}
+
+// Describe summarizes p's effective settings, for instance to assert in tests
+// how options compose.
+func (p *Policy) Describe() Description {
+	c := &p.cfg
+	d := Description{
+		Layouts:                 slices.Concat(defaultLayouts, c.layouts),
+		DurationValues:          c.relative != nil && c.relative.name == "duration",
+		MillisecondValues:       c.relative != nil && c.relative.name == "milliseconds",
+		EmitFormat:              c.emitLayout,
+		ContentTypes:            slices.Clone(c.contentTypes),
+		CorrelationHeader:       c.correlationHeader,
+		NotBeforeHeader:         c.notBeforeHeader,
+		BaggageMember:           c.baggageMember,
+		UnboundedSentinel:       c.unboundedSentinel,
+		DeadlineScale:           c.scale,
+		TrustedSource:           c.trusted != nil,
+		DefaultForUntrustedOnly: c.defaultUntrustedOnly,
+		Streaming:               c.streaming,
+		AuditSinks:              len(c.auditSinks),
+	}
+	for src, layouts := range c.sourceLayouts {
+		if d.SourceLayouts == nil {
+			d.SourceLayouts = make(map[Source][]string)
+		}
+		d.SourceLayouts[src] = slices.Clone(layouts)
+	}
+	if c.maxDeadline != nil {
+		if c.maxDeadlineFixed {
+			d.MaxDeadline = max(c.maxDeadline(nil), 0)
+		} else {
+			d.MaxDeadlineFunc = true
+		}
+	}
+	if c.defaultDeadline != nil {
+		if c.defaultFixed {
+			d.DefaultDeadline = max(c.defaultDeadline(nil), 0)
+		} else {
+			d.DefaultDeadlineFunc = true
+		}
+	}
+	if d.EmitFormat == "" {
+		d.EmitFormat = http.TimeFormat
+	}
+	if d.DeadlineScale == 0 {
+		d.DeadlineScale = 1
+	}
+	return d
+}
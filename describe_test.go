@@ -0,0 +1,90 @@
+package httpdeadline
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPolicyDescribe(t *testing.T) {
+	t.Run("zero", func(t *testing.T) {
+		p, err := NewPolicy()
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := p.Describe()
+		if got, want := d.Layouts, defaultLayouts; !slices.Equal(got, want) {
+			t.Errorf("d.Layouts = %q, want %q", got, want)
+		}
+		if got, want := d.EmitFormat, http.TimeFormat; got != want {
+			t.Errorf("d.EmitFormat = %q, want %q", got, want)
+		}
+		if got, want := d.DeadlineScale, 1.0; got != want {
+			t.Errorf("d.DeadlineScale = %v, want %v", got, want)
+		}
+		if d.MaxDeadline != 0 || d.MaxDeadlineFunc || d.DefaultDeadline != 0 || d.DefaultDeadlineFunc {
+			t.Errorf("d = %+v, want no cap or default", d)
+		}
+	})
+	t.Run("composed", func(t *testing.T) {
+		base, err := NewPolicy(
+			WithMaxDeadline(time.Hour),
+			WithDefaultDeadlineFunc(func(*http.Request) time.Duration { return time.Second }),
+			WithLayout(time.RFC3339),
+			WithContentTypeAllowlist("application/json; charset=utf-8"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := NewPolicy(base,
+			WithMaxDeadline(time.Minute),
+			WithEmitFormat(time.RFC3339Nano),
+			WithDeadlineScale(0.5),
+			WithCorrelationHeader("X-Request-ID"),
+			WithAuditSink(func(AuditRecord) {}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := p.Describe()
+		if got, want := d.MaxDeadline, time.Minute; got != want || d.MaxDeadlineFunc {
+			t.Errorf("d.MaxDeadline, d.MaxDeadlineFunc = %v, %v; want %v, false", got, d.MaxDeadlineFunc, want)
+		}
+		if got := d.DefaultDeadline; got != 0 || !d.DefaultDeadlineFunc {
+			t.Errorf("d.DefaultDeadline, d.DefaultDeadlineFunc = %v, %v; want 0, true", got, d.DefaultDeadlineFunc)
+		}
+		if got, want := d.Layouts, append(slices.Clone(defaultLayouts), time.RFC3339); !slices.Equal(got, want) {
+			t.Errorf("d.Layouts = %q, want %q", got, want)
+		}
+		if got, want := d.ContentTypes, []string{"application/json"}; !slices.Equal(got, want) {
+			t.Errorf("d.ContentTypes = %q, want %q", got, want)
+		}
+		if got, want := d.EmitFormat, time.RFC3339Nano; got != want {
+			t.Errorf("d.EmitFormat = %q, want %q", got, want)
+		}
+		if got, want := d.DeadlineScale, 0.5; got != want {
+			t.Errorf("d.DeadlineScale = %v, want %v", got, want)
+		}
+		if got, want := d.CorrelationHeader, "X-Request-ID"; got != want {
+			t.Errorf("d.CorrelationHeader = %q, want %q", got, want)
+		}
+		if got, want := d.AuditSinks, 1; got != want {
+			t.Errorf("d.AuditSinks = %v, want %v", got, want)
+		}
+	})
+	t.Run("copy", func(t *testing.T) {
+		p, err := NewPolicy(WithLayout(time.RFC3339), WithContentTypeAllowlist("application/json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := p.Describe()
+		d.Layouts[len(d.Layouts)-1] = "mutated"
+		d.ContentTypes[0] = "mutated"
+		d = p.Describe()
+		if got, want := d.Layouts[len(d.Layouts)-1], time.RFC3339; got != want {
+			t.Errorf("after mutation, d.Layouts[-1] = %q, want %q", got, want)
+		}
+		if got, want := d.ContentTypes[0], "application/json"; got != want {
+			t.Errorf("after mutation, d.ContentTypes[0] = %q, want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package httpdeadline_test
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/matttproud/httpdeadline"
+)
+
+func ExampleWithMaxDeadlineFunc() {
+	// Urgent requests (per the RFC 9218 Priority header) may use more of the
+	// budget their clients request than background ones.
+	caps := [8]time.Duration{
+		30 * time.Second, // u=0
+		20 * time.Second,
+		10 * time.Second,
+		5 * time.Second, // u=3, the default
+		5 * time.Second,
+		2 * time.Second,
+		time.Second,
+		500 * time.Millisecond, // u=7
+	}
+	capByUrgency := func(req *http.Request) time.Duration {
+		return caps[httpdeadline.Urgency(req)]
+	}
+	var teapotz http.Handler // Handler elided.
+	var mux http.ServeMux
+	mux.Handle("/teapotz", httpdeadline.FromHeader("X-MTP-Deadline", teapotz,
+		httpdeadline.WithMaxDeadlineFunc(capByUrgency)))
+}
+
+func ExampleWithMaxDeadlineFunc_slaTiers() {
+	// Each API key's SLA tier bounds the budget its callers may request.
+	// Unknown keys get the most conservative budget.
+	tiers := map[string]time.Duration{
+		"key-gold":   time.Minute,
+		"key-silver": 10 * time.Second,
+	}
+	const fallback = 2 * time.Second
+	capByKey := func(req *http.Request) time.Duration {
+		if d, ok := tiers[req.Header.Get("X-API-Key")]; ok {
+			return d
+		}
+		return fallback
+	}
+	var teapotz http.Handler // Handler elided.
+	var mux http.ServeMux
+	mux.Handle("/teapotz", httpdeadline.FromHeader("X-MTP-Deadline", teapotz,
+		httpdeadline.WithMaxDeadlineFunc(capByKey)))
+}
+
+func ExampleCommandContext() {
+	render := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		cmd := httpdeadline.CommandContext(ctx, func(cause error) {
+			log.Printf("killing renderer: %v", cause)
+		}, "render-report", "--format=pdf")
+		cmd.Stdout = w
+		if err := cmd.Run(); err != nil {
+			if errors.Is(context.Cause(ctx), httpdeadline.ErrDeadlineExceeded) {
+				// The client's budget ran out; it has stopped listening.
+				return
+			}
+			http.Error(w, "rendering failed", http.StatusInternalServerError)
+		}
+	})
+	var mux http.ServeMux
+	mux.Handle("/report", httpdeadline.FromHeader("X-MTP-Deadline", render))
+}
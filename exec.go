@@ -0,0 +1,27 @@
+package httpdeadline
+
+import (
+	"context"
+	"os/exec"
+)
+
+// CommandContext is like [exec.CommandContext]: the command is killed if ctx,
+// typically the context of a request bounded by this package's middleware, is
+// done before the command completes.  Additionally, onKill (if non-nil) is
+// called with [context.Cause] of ctx just before the command is killed, so the
+// kill can be logged or recorded; the cause matches [ErrDeadlineExceeded] when
+// the request's deadline passed and [context.Canceled] when, for instance, the
+// client disconnected.
+//
+// Callers may replace the returned command's Cancel func, but onKill is then
+// no longer called.
+func CommandContext(ctx context.Context, onKill func(cause error), name string, arg ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, arg...)
+	cmd.Cancel = func() error {
+		if onKill != nil {
+			onKill(context.Cause(ctx))
+		}
+		return cmd.Process.Kill()
+	}
+	return cmd
+}
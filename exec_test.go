@@ -0,0 +1,42 @@
+package httpdeadline
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestCommandContext(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available:", err)
+	}
+	type result struct {
+		cause   error
+		err     error
+		elapsed time.Duration
+	}
+	results := make(chan result, 1)
+	h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var cause error
+		start := time.Now()
+		cmd := CommandContext(req.Context(), func(err error) { cause = err }, "sleep", "10")
+		err := cmd.Run()
+		results <- result{cause: cause, err: err, elapsed: time.Since(start)}
+	}), WithLayout(time.RFC3339Nano))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-MTP-Deadline", time.Now().Add(100*time.Millisecond).Format(time.RFC3339Nano))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	got := <-results
+	if !errors.Is(got.cause, ErrDeadlineExceeded) {
+		t.Errorf("kill cause = %v, want %v", got.cause, ErrDeadlineExceeded)
+	}
+	if got.err == nil {
+		t.Error("cmd.Run() = nil, want error from killed process")
+	}
+	if got.elapsed > 5*time.Second {
+		t.Errorf("subprocess ran for %v, want it killed at the deadline", got.elapsed)
+	}
+}
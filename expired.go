@@ -0,0 +1,122 @@
+package httpdeadline
+
+import (
+	"container/list"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrDeadlineExpired indicates that the request's deadline had already
+	// passed when it arrived (see [WithRejectExpired]).
+	ErrDeadlineExpired = errors.New("httpdeadline: deadline expired on arrival")
+	// ErrRateLimited indicates that the client sent too many expired deadlines
+	// recently (see [WithExpiredDeadlineRateLimit]).
+	ErrRateLimited = errors.New("httpdeadline: too many expired deadlines")
+)
+
+// WithRejectExpired rejects requests whose client deadline had already passed
+// when they arrived with an error matching [ErrDeadlineExpired], instead of
+// passing them to the handler with an already-cancelled context.
+func WithRejectExpired() Option {
+	return optionFunc(func(c *config) { c.rejectExpired = true })
+}
+
+// maxTrackedClients bounds the number of clients that
+// WithExpiredDeadlineRateLimit tracks.  The least recently seen are forgotten
+// first.
+const maxTrackedClients = 4096
+
+// WithExpiredDeadlineRateLimit throttles clients that repeatedly send deadlines
+// that have already passed, which indicates a buggy or abusive client.  It
+// implies [WithRejectExpired].  Once a client, as identified by key (e.g., its
+// remote address or API key), has been rejected n times within window, all of
+// its requests are rejected with [http.StatusTooManyRequests] and an error
+// matching [ErrRateLimited] until window has elapsed since its first counted
+// rejection.  Requests for which key returns "" are not tracked.
+//
+// Tracking is memory-bounded: only the most recently seen clients are
+// remembered.  Handlers created with the same Option (or a [Policy] holding
+// it) share tracking state.
+func WithExpiredDeadlineRateLimit(n int, window time.Duration, key func(*http.Request) string) Option {
+	if n <= 0 {
+		return invalidf("non-positive expired deadline limit %d", n)
+	}
+	if window <= 0 {
+		return invalidf("non-positive expired deadline window")
+	}
+	if key == nil {
+		return invalidf("nil expired deadline key func")
+	}
+	l := &expiryLimiter{n: n, window: window, key: key}
+	l.lru, l.entries = newExpiryLRU()
+	return optionFunc(func(c *config) { c.rejectExpired, c.expiryLimiter = true, l })
+}
+
+// An expiryLimiter counts expired deadlines per client in fixed windows.
+type expiryLimiter struct {
+	n      int
+	window time.Duration
+	key    func(*http.Request) string
+
+	mu      sync.Mutex
+	lru     *list.List // Of *expiryCount, most recently seen first.
+	entries map[string]*list.Element
+}
+
+func newExpiryLRU() (*list.List, map[string]*list.Element) {
+	return list.New(), make(map[string]*list.Element)
+}
+
+type expiryCount struct {
+	key   string
+	start time.Time // Start of the current window.
+	count int
+}
+
+// limited reports whether the client identified by key is being throttled at
+// now.
+func (l *expiryLimiter) limited(key string, now time.Time) bool {
+	if key == "" {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e, ok := l.entries[key]
+	if !ok {
+		return false
+	}
+	ec := e.Value.(*expiryCount)
+	if now.Sub(ec.start) >= l.window {
+		l.lru.Remove(e)
+		delete(l.entries, key)
+		return false
+	}
+	return ec.count >= l.n
+}
+
+// record counts an expired deadline from the client identified by key at now.
+func (l *expiryLimiter) record(key string, now time.Time) {
+	if key == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.entries[key]; ok {
+		l.lru.MoveToFront(e)
+		ec := e.Value.(*expiryCount)
+		if now.Sub(ec.start) >= l.window {
+			ec.start, ec.count = now, 0
+		}
+		ec.count++
+		return
+	}
+	if l.lru.Len() >= maxTrackedClients {
+		oldest := l.lru.Back()
+		l.lru.Remove(oldest)
+		delete(l.entries, oldest.Value.(*expiryCount).key)
+	}
+	l.entries[key] = l.lru.PushFront(&expiryCount{key: key, start: now, count: 1})
+}
@@ -0,0 +1,93 @@
+package httpdeadline
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithRejectExpired(t *testing.T) {
+	clock := func() time.Time { return now }
+	for _, test := range []struct {
+		Name     string
+		Deadline time.Time
+
+		Code int
+		Err  error
+	}{
+		{Name: "future", Deadline: now.Add(time.Second), Code: 200},
+		{Name: "now", Deadline: now, Code: 400, Err: ErrDeadlineExpired},
+		{Name: "past", Deadline: now.Add(-time.Second), Code: 400, Err: ErrDeadlineExpired},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var got AuditRecord
+			h := FromHeader("X-MTP-Deadline", new(spyHandler), WithClock(clock), WithRejectExpired(),
+				WithAuditSink(func(rec AuditRecord) { got = rec }))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-MTP-Deadline", asTimeFormat(test.Deadline))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, test.Code; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+			if !errors.Is(got.Err, test.Err) || (test.Err == nil) != (got.Err == nil) {
+				t.Errorf("rec.Err = %v, want %v", got.Err, test.Err)
+			}
+		})
+	}
+}
+
+func TestWithExpiredDeadlineRateLimit(t *testing.T) {
+	clock := now
+	h := FromHeader("X-MTP-Deadline", new(spyHandler),
+		WithClock(func() time.Time { return clock }),
+		WithExpiredDeadlineRateLimit(3, time.Minute, func(req *http.Request) string { return req.Header.Get("X-API-Key") }))
+	serve := func(key string, deadline time.Time) int {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-MTP-Deadline", asTimeFormat(deadline))
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	stale, fresh := now.Add(-time.Hour), now.Add(time.Hour)
+	for i := range 3 {
+		if got, want := serve("abuser", stale), 400; got != want {
+			t.Fatalf("expired request %d: code = %v, want %v", i, got, want)
+		}
+	}
+	if got, want := serve("abuser", fresh), http.StatusTooManyRequests; got != want {
+		t.Errorf("after threshold, fresh request: code = %v, want %v", got, want)
+	}
+	if got, want := serve("abuser", stale), http.StatusTooManyRequests; got != want {
+		t.Errorf("after threshold, expired request: code = %v, want %v", got, want)
+	}
+	if got, want := serve("bystander", fresh), 200; got != want {
+		t.Errorf("other client: code = %v, want %v", got, want)
+	}
+	clock = clock.Add(time.Minute)
+	if got, want := serve("abuser", fresh.Add(time.Minute)), 200; got != want {
+		t.Errorf("after window, fresh request: code = %v, want %v", got, want)
+	}
+}
+
+func TestExpiryLimiterBounded(t *testing.T) {
+	l := &expiryLimiter{n: 1, window: time.Hour}
+	l.lru, l.entries = newExpiryLRU()
+	for i := range maxTrackedClients + 10 {
+		l.record(fmt.Sprint(i), now)
+	}
+	if got, want := len(l.entries), maxTrackedClients; got != want {
+		t.Errorf("len(l.entries) = %v, want %v", got, want)
+	}
+	if l.limited("0", now) {
+		t.Error("least recently seen client still tracked")
+	}
+	if !l.limited(fmt.Sprint(maxTrackedClients+9), now) {
+		t.Error("most recently seen client not tracked")
+	}
+}
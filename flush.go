@@ -0,0 +1,47 @@
+package httpdeadline
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WithFlushDeadlineEnforcement checks the request's deadline whenever the
+// handler flushes a streamed response, so that handlers streaming partial
+// results stop at a flush boundary once the deadline passes rather than stream
+// on.  After the deadline, flushes do nothing and report an error matching
+// [ErrDeadlineExceeded].  The check applies in [WithStreamingMode], too, where
+// the request's context is not cancelled.
+//
+// Handlers must observe the error for this to have any effect, so flush with
+// [http.ResponseController.Flush] and stop streaming when it fails;
+// [http.Flusher.Flush] cannot report errors:
+//
+//	rc := http.NewResponseController(w)
+//	for result := range results {
+//		fmt.Fprintln(w, result)
+//		if err := rc.Flush(); err != nil {
+//			return
+//		}
+//	}
+func WithFlushDeadlineEnforcement() Option {
+	return optionFunc(func(c *config) { c.flushDeadline = true })
+}
+
+// A flushWriter enforces a deadline on flushes.
+type flushWriter struct {
+	http.ResponseWriter
+	deadline time.Time
+}
+
+func (w *flushWriter) FlushError() error {
+	if now := time.Now(); !now.Before(w.deadline) {
+		return fmt.Errorf("%w: flush %v after deadline", ErrDeadlineExceeded, now.Sub(w.deadline))
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *flushWriter) Flush() { w.FlushError() }
+
+// Unwrap lets [http.ResponseController] reach the underlying writer.
+func (w *flushWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
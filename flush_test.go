@@ -0,0 +1,57 @@
+package httpdeadline
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWithFlushDeadlineEnforcement(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		Opts []Option
+	}{
+		{Name: "cancelling"},
+		{Name: "streaming", Opts: []Option{WithStreamingMode(func(*http.Request) {})}},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			flushErr := make(chan error, 1)
+			h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				rc := http.NewResponseController(w)
+				for i := 0; ; i++ {
+					fmt.Fprintln(w, i)
+					if err := rc.Flush(); err != nil {
+						flushErr <- err
+						return
+					}
+					time.Sleep(10 * time.Millisecond)
+				}
+			}), append(test.Opts, WithLayout(time.RFC3339Nano), WithFlushDeadlineEnforcement())...)
+			srv := newServer(t, h)
+			req := newGetRequest(t, urlOf(t, srv))
+			deadline := time.Now().Add(100 * time.Millisecond)
+			req.Header.Set("X-MTP-Deadline", deadline.Format(time.RFC3339Nano))
+			resp, err := newClient().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var lines int
+			for s := bufio.NewScanner(resp.Body); s.Scan(); {
+				lines++
+			}
+			if err := <-flushErr; !errors.Is(err, ErrDeadlineExceeded) {
+				t.Errorf("flush error = %v, want %v", err, ErrDeadlineExceeded)
+			}
+			if time.Now().Before(deadline) {
+				t.Error("flushing stopped before deadline")
+			}
+			if lines < 2 || lines > 20 {
+				t.Errorf("streamed %d lines, want a few before the deadline", lines)
+			}
+		})
+	}
+}
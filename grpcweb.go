@@ -0,0 +1,46 @@
+package httpdeadline
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WithGRPCWebStatus makes the middleware answer gRPC-Web requests whose
+// deadline passes before the wrapped handler writes anything with a gRPC-Web
+// DEADLINE_EXCEEDED status, which gRPC-Web clients interpret correctly, rather
+// than the empty 200 response that the handler returning would otherwise
+// produce.  The status uses the trailers-only framing of the gRPC-Web protocol:
+// a 200 response with no body whose headers carry
+//
+//	grpc-status: 4
+//	grpc-message: deadline exceeded
+//
+// and whose Content-Type echoes the request's (defaulting to
+// "application/grpc-web+proto").  Handlers that have begun writing a response
+// when the deadline passes must end it with their own status trailer, since
+// the middleware cannot tell whether they did.
+func WithGRPCWebStatus() Option {
+	return optionFunc(func(c *config) { c.grpcWebStatus = true })
+}
+
+// gRPC status code for DEADLINE_EXCEEDED.
+const grpcDeadlineExceeded = "4"
+
+// writeGRPCWebDeadline writes a trailers-only gRPC-Web DEADLINE_EXCEEDED
+// response to w if the handler wrote nothing to it before deadline passed.
+func writeGRPCWebDeadline(w *writeTracker, req *http.Request, deadline time.Time) {
+	if w.wrote || time.Now().Before(deadline) {
+		return
+	}
+	contentType := req.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "application/grpc-web") {
+		contentType = "application/grpc-web+proto"
+	}
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", contentType)
+	h.Set("Grpc-Status", grpcDeadlineExceeded)
+	h.Set("Grpc-Message", "deadline exceeded")
+	w.WriteHeader(http.StatusOK)
+}
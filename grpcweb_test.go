@@ -0,0 +1,58 @@
+package httpdeadline
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithGRPCWebStatus(t *testing.T) {
+	for _, test := range []struct {
+		Name    string
+		Handler http.HandlerFunc
+
+		Status string
+	}{
+		{
+			Name: "expired-unwritten",
+			Handler: func(w http.ResponseWriter, req *http.Request) {
+				<-req.Context().Done()
+			},
+			Status: "4",
+		},
+		{
+			Name: "completed",
+			Handler: func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Grpc-Status", "0")
+				w.WriteHeader(http.StatusOK)
+			},
+			Status: "0",
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			h := FromHeader("X-MTP-Deadline", test.Handler, WithLayout(time.RFC3339Nano), WithGRPCWebStatus())
+			req := httptest.NewRequest("POST", "/teapot.v1.Teapot/Brew", nil)
+			req.Header.Set("Content-Type", "application/grpc-web-text")
+			req.Header.Set("X-MTP-Deadline", time.Now().Add(50*time.Millisecond).Format(time.RFC3339Nano))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			resp := rec.Result()
+			if got, want := resp.StatusCode, http.StatusOK; got != want {
+				t.Errorf("resp.StatusCode = %v, want %v", got, want)
+			}
+			if got, want := resp.Header.Get("Grpc-Status"), test.Status; got != want {
+				t.Errorf("grpc-status = %q, want %q", got, want)
+			}
+			if test.Status != "4" {
+				return
+			}
+			if got, want := resp.Header.Get("Content-Type"), "application/grpc-web-text"; got != want {
+				t.Errorf("Content-Type = %q, want %q", got, want)
+			}
+			if got, want := resp.Header.Get("Grpc-Message"), "deadline exceeded"; got != want {
+				t.Errorf("grpc-message = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package httpdeadline
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// A BudgetHighWater tracks the largest budget (the time between a request's
+// receipt and its deadline) that clients requested, before any capping, which
+// shows the worst-case work that clients try to commit the server to.  Attach
+// it to handlers with [WithMaxObservedBudget].
+//
+// BudgetHighWater implements [expvar.Var], rendering the mark in
+// milliseconds, so it can be published with [expvar.Publish].  Its zero value
+// is ready for use.
+type BudgetHighWater struct {
+	mark atomic.Int64 // A time.Duration.
+}
+
+// observe raises the mark to budget if it is higher.
+func (h *BudgetHighWater) observe(budget time.Duration) {
+	for {
+		cur := h.mark.Load()
+		if int64(budget) <= cur || h.mark.CompareAndSwap(cur, int64(budget)) {
+			return
+		}
+	}
+}
+
+// Snapshot reports the largest budget requested since h was created or last
+// reset, or zero if none was.
+func (h *BudgetHighWater) Snapshot() time.Duration { return time.Duration(h.mark.Load()) }
+
+// Reset clears the mark, for instance at the start of each reporting window,
+// and reports its previous value.
+func (h *BudgetHighWater) Reset() time.Duration { return time.Duration(h.mark.Swap(0)) }
+
+// String renders the mark in milliseconds for [expvar].
+func (h *BudgetHighWater) String() string {
+	return strconv.FormatInt(h.Snapshot().Milliseconds(), 10)
+}
+
+// WithMaxObservedBudget records the budget of each client deadline the handler
+// applies in h, as the client requested it (before caps like
+// [WithMaxDeadline]).  Server defaults are not recorded.
+func WithMaxObservedBudget(h *BudgetHighWater) Option {
+	if h == nil {
+		return invalidf("nil budget high-water mark")
+	}
+	return WithAuditSink(func(rec AuditRecord) {
+		if rec.Outcome == OutcomeApplied || rec.Outcome == OutcomeClamped {
+			h.observe(rec.Requested.Sub(rec.Time))
+		}
+	})
+}
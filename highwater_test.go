@@ -0,0 +1,74 @@
+package httpdeadline
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithMaxObservedBudget(t *testing.T) {
+	var hw BudgetHighWater
+	h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+		WithClock(func() time.Time { return now }),
+		WithMaxDeadline(time.Minute),
+		WithDefaultDeadline(time.Hour),
+		WithMaxObservedBudget(&hw))
+	serve := func(budget time.Duration) {
+		req := httptest.NewRequest("GET", "/", nil)
+		if budget != 0 {
+			req.Header.Set("X-MTP-Deadline", asTimeFormat(now.Add(budget)))
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	for _, test := range []struct {
+		Name    string
+		Budgets []time.Duration
+
+		Mark time.Duration
+	}{
+		{Name: "none"},
+		{Name: "single", Budgets: []time.Duration{10 * time.Second}, Mark: 10 * time.Second},
+		{Name: "max", Budgets: []time.Duration{20 * time.Second, 45 * time.Second, 5 * time.Second}, Mark: 45 * time.Second},
+		{Name: "before-capping", Budgets: []time.Duration{5 * time.Minute, time.Second}, Mark: 5 * time.Minute},
+		{Name: "defaults-ignored", Budgets: []time.Duration{0, time.Second}, Mark: time.Second},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			hw.Reset()
+			for _, budget := range test.Budgets {
+				serve(budget)
+			}
+			if got, want := hw.Snapshot(), test.Mark; got != want {
+				t.Errorf("hw.Snapshot() = %v, want %v", got, want)
+			}
+			if got, want := hw.Reset(), test.Mark; got != want {
+				t.Errorf("hw.Reset() = %v, want %v", got, want)
+			}
+			if got, want := hw.Snapshot(), time.Duration(0); got != want {
+				t.Errorf("hw.Snapshot() after reset = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestBudgetHighWaterConcurrent(t *testing.T) {
+	var hw BudgetHighWater
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hw.observe(time.Duration(i) * time.Millisecond)
+		}()
+	}
+	wg.Wait()
+	if got, want := hw.Snapshot(), 99*time.Millisecond; got != want {
+		t.Errorf("hw.Snapshot() = %v, want %v", got, want)
+	}
+	var _ expvar.Var = &hw
+	if got, want := hw.String(), "99"; got != want {
+		t.Errorf("hw.String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,58 @@
+package httpdeadline
+
+import "sync/atomic"
+
+// A PolicyHolder holds a [Policy] that can be swapped at runtime (e.g., when a
+// configuration system pushes an update), so operators can reload the entire
+// policy without restarting or re-registering handlers.  See
+// [WithPolicyHolder].  The zero value holds no Policy, which behaves like a
+// Policy created without options.  A PolicyHolder is safe for concurrent use.
+type PolicyHolder struct {
+	p atomic.Pointer[Policy]
+}
+
+// NewPolicyHolder creates a PolicyHolder that initially holds p.
+func NewPolicyHolder(p *Policy) *PolicyHolder {
+	var h PolicyHolder
+	h.Store(p)
+	return &h
+}
+
+// Load returns the current Policy, which is nil if none has been stored.
+func (h *PolicyHolder) Load() *Policy { return h.p.Load() }
+
+// Store replaces the current Policy with p.  Requests that handlers have
+// already begun deciding continue under the previous Policy.
+func (h *PolicyHolder) Store(p *Policy) { h.p.Store(p) }
+
+// WithPolicyHolder makes the handler decide each request under the Policy that
+// h holds at the time, so stores take effect on the next request.  Reading the
+// Policy is lock-free.  The held Policy supersedes all other options given to
+// the handler, so configure everything through it.  For handlers created with
+// [Policy.Handler], that includes the sources of [Policy.WithSources]: those
+// of the held Policy, if it has any, replace the handler's own.  Other
+// handlers ignore them.
+func WithPolicyHolder(h *PolicyHolder) Option {
+	if h == nil {
+		return invalidf("nil policy holder")
+	}
+	return optionFunc(func(c *config) { c.holder = h })
+}
+
+// current returns h as configured by its policy holder, if any.
+func (h *handler) current() *handler {
+	ph := h.cfg.holder
+	if ph == nil {
+		return h
+	}
+	hh := *h
+	if p := ph.Load(); p != nil {
+		hh.cfg = p.cfg
+		if h.lookup == nil && p.sources != nil {
+			hh.chain = p.sources
+		}
+	} else {
+		hh.cfg = config{}
+	}
+	return &hh
+}
@@ -0,0 +1,125 @@
+package httpdeadline
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestWithPolicyHolder(t *testing.T) {
+	mustPolicy := func(opts ...Option) *Policy {
+		t.Helper()
+		p, err := NewPolicy(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	clock := func() time.Time { return now }
+	holder := NewPolicyHolder(mustPolicy(WithClock(clock), WithMaxDeadline(time.Minute)))
+	var spy spyHandler
+	h := FromHeader("X-MTP-Deadline", &spy, WithPolicyHolder(holder))
+	serve := func(val string) int {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-MTP-Deadline", val)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	far := now.Add(time.Hour)
+
+	if got, want := serve(asTimeFormat(far)), 200; got != want {
+		t.Fatalf("code = %v, want %v", got, want)
+	}
+	if got, want := spy.Deadline, now.Add(time.Minute); !got.Equal(want) {
+		t.Errorf("under first policy, spy.Deadline = %v, want %v", got, want)
+	}
+	if got, want := serve(far.Format(time.RFC3339)), 400; got != want {
+		t.Errorf("under first policy, RFC 3339 code = %v, want %v", got, want)
+	}
+
+	holder.Store(mustPolicy(WithClock(clock), WithMaxDeadline(10*time.Second), WithLayout(time.RFC3339)))
+	if got, want := serve(asTimeFormat(far)), 200; got != want {
+		t.Fatalf("code = %v, want %v", got, want)
+	}
+	if got, want := spy.Deadline, now.Add(10*time.Second); !got.Equal(want) {
+		t.Errorf("under second policy, spy.Deadline = %v, want %v", got, want)
+	}
+	if got, want := serve(far.Format(time.RFC3339)), 200; got != want {
+		t.Errorf("under second policy, RFC 3339 code = %v, want %v", got, want)
+	}
+
+	holder.Store(nil)
+	if got, want := serve(asTimeFormat(far)), 200; got != want {
+		t.Fatalf("code = %v, want %v", got, want)
+	}
+	if got, want := spy.Deadline, far; !got.Equal(want) {
+		t.Errorf("without policy, spy.Deadline = %v, want %v", got, want)
+	}
+}
+
+func TestWithPolicyHolderSources(t *testing.T) {
+	withSources := func(srcs ...Extractor) *Policy {
+		t.Helper()
+		p, err := NewPolicy(WithClock(func() time.Time { return now }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p, err = p.WithSources(srcs...); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	holder := NewPolicyHolder(withSources(HeaderSource("X-MTP-Deadline")))
+	outer, err := NewPolicy(WithPolicyHolder(holder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	outer, err = outer.WithSources(CookieSource("deadline"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spy spyHandler
+	h := outer.Handler(&spy)
+	deadline := now.Add(time.Minute)
+	for _, test := range []struct {
+		Name   string
+		Policy *Policy // Stored before the requests are served.
+		Header string  // The value of X-MTP-Deadline, if any.
+		Query  string  // The value of the query parameter deadline, if any.
+		Cookie string  // The value of the cookie deadline, if any.
+
+		OK bool
+	}{
+		{Name: "held-header", Header: asTimeFormat(deadline), OK: true},
+		{Name: "held-query-ignored", Query: asTimeFormat(deadline)},
+		{Name: "replaced-query", Policy: withSources(QuerySource("deadline")), Query: asTimeFormat(deadline), OK: true},
+		{Name: "replaced-header-ignored", Header: asTimeFormat(deadline)},
+		// A held Policy without sources leaves the handler's own in place.
+		{Name: "own-cookie", Policy: withSources(), Cookie: asTimeFormat(deadline), OK: true},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			if test.Policy != nil {
+				holder.Store(test.Policy)
+			}
+			spy = spyHandler{}
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.Header != "" {
+				req.Header.Set("X-MTP-Deadline", test.Header)
+			}
+			if test.Query != "" {
+				req.URL.RawQuery = url.Values{"deadline": {test.Query}}.Encode()
+			}
+			if test.Cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "deadline", Value: test.Cookie})
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if spy.OK != test.OK || (test.OK && !spy.Deadline.Equal(deadline)) {
+				t.Errorf("spy.Deadline = %v, %v; want %v, %v", spy.Deadline, spy.OK, deadline, test.OK)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Package httpdeadlinetest provides utilities for testing how handlers that
+// use package httpdeadline enforce deadlines.  It is intended for use in tests
+// only.
+package httpdeadlinetest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// SlowHandler returns a handler that takes d to respond with
+// [http.StatusOK].  If its request's context is done first, it returns
+// immediately without writing anything, which leaves the response to whatever
+// enforces the deadline around it (e.g., [http.TimeoutHandler]).  Because it
+// waits on the context rather than sleeping blindly, tests built on it finish
+// as soon as the deadline fires.
+func SlowHandler(d time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			w.WriteHeader(http.StatusOK)
+		case <-req.Context().Done():
+		}
+	})
+}
+
+// AssertStatus serves req with h and reports an error to t unless the response
+// has status want.  It returns the recorded response for further inspection.
+func AssertStatus(t testing.TB, h http.Handler, req *http.Request, want int) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got := rec.Code; got != want {
+		t.Errorf("%v %v: status = %v, want %v", req.Method, req.URL, got, want)
+	}
+	return rec
+}
+
+// AssertEnforcedWithin is like [AssertStatus] but additionally reports an error
+// to t unless h responded within the given duration.  Pair it with a
+// [SlowHandler] that takes much longer than that to verify that the deadline,
+// not the handler, ended the request.
+func AssertEnforcedWithin(t testing.TB, h http.Handler, req *http.Request, want int, within time.Duration) *httptest.ResponseRecorder {
+	t.Helper()
+	start := time.Now()
+	rec := AssertStatus(t, h, req, want)
+	if elapsed := time.Since(start); elapsed > within {
+		t.Errorf("%v %v: responded after %v, want within %v", req.Method, req.URL, elapsed, within)
+	}
+	return rec
+}
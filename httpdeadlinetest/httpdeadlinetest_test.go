@@ -0,0 +1,51 @@
+package httpdeadlinetest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/matttproud/httpdeadline"
+	"github.com/matttproud/httpdeadline/httpdeadlinetest"
+)
+
+func TestSlowHandler(t *testing.T) {
+	t.Run("completes", func(t *testing.T) {
+		h := httpdeadlinetest.SlowHandler(time.Millisecond)
+		httpdeadlinetest.AssertStatus(t, h, httptest.NewRequest("GET", "/", nil), http.StatusOK)
+	})
+	t.Run("enforced", func(t *testing.T) {
+		h := httpdeadline.FromHeader("X-MTP-Deadline",
+			http.TimeoutHandler(httpdeadlinetest.SlowHandler(time.Hour), time.Hour, "timed out"),
+			httpdeadline.WithLayout(time.RFC3339Nano))
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-MTP-Deadline", time.Now().Add(50*time.Millisecond).Format(time.RFC3339Nano))
+		httpdeadlinetest.AssertEnforcedWithin(t, h, req, http.StatusServiceUnavailable, 5*time.Second)
+	})
+}
+
+func TestAssertStatus(t *testing.T) {
+	var ft fakeT
+	httpdeadlinetest.AssertStatus(&ft, httpdeadlinetest.SlowHandler(0), httptest.NewRequest("GET", "/", nil), http.StatusTeapot)
+	if !ft.failed {
+		t.Error("AssertStatus() did not report mismatched status")
+	}
+}
+
+func TestAssertEnforcedWithin(t *testing.T) {
+	var ft fakeT
+	h := httpdeadlinetest.SlowHandler(20 * time.Millisecond)
+	httpdeadlinetest.AssertEnforcedWithin(&ft, h, httptest.NewRequest("GET", "/", nil), http.StatusOK, time.Millisecond)
+	if !ft.failed {
+		t.Error("AssertEnforcedWithin() did not report slow response")
+	}
+}
+
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (t *fakeT) Helper()               {}
+func (t *fakeT) Errorf(string, ...any) { t.failed = true }
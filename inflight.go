@@ -0,0 +1,74 @@
+package httpdeadline
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+)
+
+// An InFlight gauges the requests currently being served under a deadline,
+// grouped by how tight their budget (the time between receipt and deadline)
+// was when they were admitted.  Load balancers and load shedders can use it to
+// tell cheap, urgent work from long-running work.  Attach it to handlers with
+// [WithInFlight].
+//
+// InFlight implements [expvar.Var], so it can be published with
+// [expvar.Publish].  Its zero value is ready for use.
+type InFlight struct {
+	buckets [4]atomic.Int64
+}
+
+// An InFlightSnapshot is a point-in-time reading of an [InFlight].
+type InFlightSnapshot struct {
+	Under100ms int64 `json:"under_100ms"` // Budget < 100ms.
+	Under1s    int64 `json:"under_1s"`    // 100ms <= budget < 1s.
+	Under10s   int64 `json:"under_10s"`   // 1s <= budget < 10s.
+	Over10s    int64 `json:"over_10s"`    // Budget >= 10s.
+}
+
+func inFlightBucket(budget time.Duration) int {
+	switch {
+	case budget < 100*time.Millisecond:
+		return 0
+	case budget < time.Second:
+		return 1
+	case budget < 10*time.Second:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// track counts a request with the given budget as in flight until the
+// returned func is called.
+func (f *InFlight) track(budget time.Duration) (done func()) {
+	bucket := &f.buckets[inFlightBucket(budget)]
+	bucket.Add(1)
+	return func() { bucket.Add(-1) }
+}
+
+// Snapshot reads the current counts.  Counts are read individually, so a
+// snapshot taken while requests start and finish may not reflect one instant.
+func (f *InFlight) Snapshot() InFlightSnapshot {
+	return InFlightSnapshot{
+		Under100ms: f.buckets[0].Load(),
+		Under1s:    f.buckets[1].Load(),
+		Under10s:   f.buckets[2].Load(),
+		Over10s:    f.buckets[3].Load(),
+	}
+}
+
+// String renders the snapshot as JSON for [expvar].
+func (f *InFlight) String() string {
+	b, _ := json.Marshal(f.Snapshot())
+	return string(b)
+}
+
+// WithInFlight counts requests served under a deadline in f while they are in
+// flight.
+func WithInFlight(f *InFlight) Option {
+	if f == nil {
+		return invalidf("nil in-flight gauge")
+	}
+	return optionFunc(func(c *config) { c.inFlight = f })
+}
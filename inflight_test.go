@@ -0,0 +1,58 @@
+package httpdeadline
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithInFlight(t *testing.T) {
+	var gauge InFlight
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		started.Done()
+		<-release
+	}), WithLayout(time.RFC3339Nano), WithInFlight(&gauge))
+
+	budgets := []time.Duration{
+		-time.Second, 50 * time.Millisecond, // Under100ms
+		500 * time.Millisecond,                            // Under1s
+		5 * time.Second, 5 * time.Second, 5 * time.Second, // Under10s
+		time.Hour, // Over10s
+	}
+	var finished sync.WaitGroup
+	started.Add(len(budgets) + 1)
+	finished.Add(len(budgets) + 1)
+	serve := func(deadline string) {
+		defer finished.Done()
+		req := httptest.NewRequest("GET", "/", nil)
+		if deadline != "" {
+			req.Header.Set("X-MTP-Deadline", deadline)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	for _, budget := range budgets {
+		go serve(time.Now().Add(budget).Format(time.RFC3339Nano))
+	}
+	go serve("") // Requests without deadlines are not counted.
+	started.Wait()
+
+	want := InFlightSnapshot{Under100ms: 2, Under1s: 1, Under10s: 3, Over10s: 1}
+	if got := gauge.Snapshot(); got != want {
+		t.Errorf("gauge.Snapshot() = %+v, want %+v", got, want)
+	}
+	var decoded InFlightSnapshot
+	if err := json.Unmarshal([]byte(gauge.String()), &decoded); err != nil || decoded != want {
+		t.Errorf("gauge.String() = %q (%v), want JSON of %+v", gauge.String(), err, want)
+	}
+
+	close(release)
+	finished.Wait()
+	if got, want := gauge.Snapshot(), (InFlightSnapshot{}); got != want {
+		t.Errorf("gauge.Snapshot() after completion = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+package httpdeadline
+
+import (
+	"errors"
+	"expvar"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// formatHits counts successful parses by layout name.  It is published lazily
+// so that importing the package alone does not add variables to expvar.
+var formatHits = sync.OnceValue(func() *expvar.Map {
+	return expvar.NewMap("httpdeadline.formats")
+})
+
+// rejections counts rejected requests by reason, published lazily like
+// formatHits.
+var rejections = sync.OnceValue(func() *expvar.Map {
+	return expvar.NewMap("httpdeadline.rejections")
+})
+
+// layoutNames names well-known layouts for metrics.  Other layouts are named
+// by the layout itself.
+var layoutNames = map[string]string{
+	http.TimeFormat:  "TimeFormat",
+	time.RFC850:      "RFC850",
+	time.ANSIC:       "ANSIC",
+	time.UnixDate:    "UnixDate",
+	time.RubyDate:    "RubyDate",
+	time.RFC822:      "RFC822",
+	time.RFC822Z:     "RFC822Z",
+	time.RFC1123:     "RFC1123",
+	time.RFC1123Z:    "RFC1123Z",
+	time.RFC3339:     "RFC3339",
+	time.RFC3339Nano: "RFC3339Nano",
+	time.Kitchen:     "Kitchen",
+	time.DateTime:    "DateTime",
+}
+
+func layoutName(layout string) string {
+	if name, ok := layoutNames[layout]; ok {
+		return name
+	}
+	return layout
+}
+
+// WithFormatMetrics counts how many deadline values parse with each accepted
+// format, which helps judge whether a format (see [WithLayout]) is still in
+// use.  The counts are published through [expvar] as the map
+// "httpdeadline.formats", keyed by the format's name (e.g., "RFC850") for the
+// [time] package's layouts and [http.TimeFormat], "duration" for
+// [WithDurationValues], "milliseconds" for [WithMillisecondValues], and by the
+// layout itself otherwise.  The counts are process-wide and shared by all
+// handlers.
+func WithFormatMetrics() Option {
+	return optionFunc(func(c *config) { c.formatMetrics = true })
+}
+
+// reasons describes rejection reasons for metrics and problem reports.
+var reasons = []struct {
+	err   error
+	ident string // Name of the exported variable holding err.
+	name  string
+	title string
+}{
+	{ErrEmptyValue, "ErrEmptyValue", "empty", "Empty deadline"},
+	{ErrParse, "ErrParse", "parse", "Malformed deadline"},
+	{ErrBudgetTooSmall, "ErrBudgetTooSmall", "budget_too_small", "Deadline budget too small"},
+	{ErrTooEarly, "ErrTooEarly", "too_early", "Request too early"},
+	{ErrNonPositiveBudget, "ErrNonPositiveBudget", "non_positive_budget", "Non-positive deadline budget"},
+	{ErrBudgetOverflow, "ErrBudgetOverflow", "budget_overflow", "Deadline budget overflow"},
+	{ErrDeadlineExpired, "ErrDeadlineExpired", "deadline_expired", "Deadline expired on arrival"},
+	{ErrRateLimited, "ErrRateLimited", "rate_limited", "Too many expired deadlines"},
+	{ErrMissingCapability, "ErrMissingCapability", "missing_capability", "Caller may not set deadlines"},
+	{ErrBudgetExhausted, "ErrBudgetExhausted", "budget_exhausted", "Aggregate deadline budget exhausted"},
+}
+
+// reasonName names the reason for the rejection err.
+func reasonName(err error) string {
+	for _, r := range reasons {
+		if errors.Is(err, r.err) {
+			return r.name
+		}
+	}
+	return "other"
+}
+
+// WithReasonMetrics counts rejected requests by reason.  The counts are
+// published through [expvar] as the map "httpdeadline.rejections", keyed by
+// reason: "empty" ([ErrEmptyValue]), "parse" ([ErrParse]), "budget_too_small"
+// ([ErrBudgetTooSmall]), "too_early" ([ErrTooEarly]), "non_positive_budget"
+// ([ErrNonPositiveBudget]), "budget_overflow" ([ErrBudgetOverflow]),
+// "deadline_expired" ([ErrDeadlineExpired]), "rate_limited"
+// ([ErrRateLimited]), "missing_capability" ([ErrMissingCapability]), and
+// "budget_exhausted" ([ErrBudgetExhausted]).  The counts are process-wide and
+// shared by all handlers.  For per-handler accounting, use [WithAuditSink] and
+// classify [AuditRecord.Err] with [errors.Is].
+func WithReasonMetrics() Option {
+	return optionFunc(func(c *config) { c.reasonMetrics = true })
+}
+
+// WithClampHistogram calls observe with how much policy shortened the client's
+// deadline (the requested deadline less the effective one) whenever it was
+// clamped (e.g., by [WithMaxDeadline]), which helps judge whether a cap is too
+// aggressive.  observe is called synchronously like an [WithAuditSink] sink,
+// so it should be fast, e.g., recording into a histogram.
+func WithClampHistogram(observe func(reduction time.Duration)) Option {
+	if observe == nil {
+		return invalidf("nil clamp histogram func")
+	}
+	return WithAuditSink(func(rec AuditRecord) {
+		if rec.Outcome == OutcomeClamped {
+			observe(rec.Requested.Sub(rec.Effective))
+		}
+	})
+}
+
+// WithSLOObserver calls observe after the wrapped handler returns from each
+// request served under a deadline, reporting whether the handler met that
+// deadline and, if not, by how much it overran it, so that deadline adherence
+// can be fed into an SLO tracker.  route is the [http.ServeMux] pattern that
+// matched the request (see [http.Request.Pattern]), so mount the middleware
+// beneath the ServeMux rather than around it; route is empty otherwise.
+func WithSLOObserver(observe func(route string, met bool, overBy time.Duration)) Option {
+	if observe == nil {
+		return invalidf("nil SLO observer")
+	}
+	return optionFunc(func(c *config) { c.sloObserver = observe })
+}
+
+// WithUtilizationObserver calls observe after the wrapped handler returns from
+// each request served under a deadline with the fraction of the applied
+// budget (the time between the request's receipt and its deadline) that the
+// handler ran for, which shows how close to their deadlines handlers routinely
+// run independent of raw latency.  Ratios over 1 mean the handler overran its
+// deadline; requests whose budget had already run out when they were received
+// report [math.Inf].  Requests without deadlines are not observed.
+func WithUtilizationObserver(observe func(req *http.Request, ratio float64)) Option {
+	if observe == nil {
+		return invalidf("nil utilization observer")
+	}
+	return optionFunc(func(c *config) { c.utilizationObserver = observe })
+}
+
+// utilization reports the fraction of budget that elapsed consumed.
+func utilization(elapsed, budget time.Duration) float64 {
+	if budget <= 0 {
+		return math.Inf(1)
+	}
+	return float64(elapsed) / float64(budget)
+}
@@ -0,0 +1,239 @@
+package httpdeadline
+
+import (
+	"expvar"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func formatCount(name string) int64 {
+	if v, ok := formatHits().Get(name).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestWithFormatMetrics(t *testing.T) {
+	names := []string{"TimeFormat", "RFC850", "ANSIC", "RFC3339"}
+	before := make(map[string]int64)
+	for _, name := range names {
+		before[name] = formatCount(name)
+	}
+	h := FromHeader("X-MTP-Deadline", new(spyHandler), WithLayout(time.RFC3339), WithFormatMetrics())
+	for _, val := range []string{asRFC850(now), "garbage"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-MTP-Deadline", val)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	for _, name := range names {
+		want := before[name]
+		if name == "RFC850" {
+			want++
+		}
+		if got := formatCount(name); got != want {
+			t.Errorf("count for %v = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func rejectionCount(name string) int64 {
+	if v, ok := rejections().Get(name).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestWithReasonMetrics(t *testing.T) {
+	future := asTimeFormat(time.Now().Add(time.Hour))
+	expired := asTimeFormat(now)
+	tested := make(map[string]bool)
+	for _, test := range []struct {
+		Reason string
+		Opts   []Option
+		Header http.Header
+		// Prime, if set, is sent first to bring the handler into the state
+		// that rejects Header.
+		Prime http.Header
+	}{
+		{Reason: "empty", Header: http.Header{"X-Mtp-Deadline": {""}}},
+		{Reason: "parse", Header: http.Header{"X-Mtp-Deadline": {"garbage"}}},
+		{
+			Reason: "budget_too_small",
+			Opts:   []Option{WithMinimumServiceTime(func(*http.Request) time.Duration { return time.Minute })},
+			Header: http.Header{"X-Mtp-Deadline": {expired}},
+		},
+		{
+			Reason: "too_early",
+			Opts:   []Option{WithNotBeforeHeader("X-MTP-Not-Before")},
+			Header: http.Header{"X-Mtp-Not-Before": {future}},
+		},
+		{Reason: "non_positive_budget", Opts: []Option{WithDurationValues()}, Header: http.Header{"X-Mtp-Deadline": {"-5s"}}},
+		{Reason: "budget_overflow", Opts: []Option{WithDurationValues()}, Header: http.Header{"X-Mtp-Deadline": {"1000000h"}}},
+		{Reason: "deadline_expired", Opts: []Option{WithRejectExpired()}, Header: http.Header{"X-Mtp-Deadline": {expired}}},
+		{
+			Reason: "rate_limited",
+			Opts:   []Option{WithExpiredDeadlineRateLimit(1, time.Hour, func(*http.Request) string { return "client" })},
+			Prime:  http.Header{"X-Mtp-Deadline": {expired}},
+			Header: http.Header{"X-Mtp-Deadline": {future}},
+		},
+		{
+			Reason: "missing_capability",
+			Opts:   []Option{WithRequireCapability(func(*http.Request) bool { return false })},
+			Header: http.Header{"X-Mtp-Deadline": {future}},
+		},
+		{
+			Reason: "budget_exhausted",
+			Opts:   []Option{WithBudgetRateLimit(NewBudgetBucket(time.Second, time.Nanosecond))},
+			Prime:  http.Header{"X-Mtp-Deadline": {future}},
+			Header: http.Header{"X-Mtp-Deadline": {future}},
+		},
+	} {
+		tested[test.Reason] = true
+		t.Run(test.Reason, func(t *testing.T) {
+			h := FromHeader("X-MTP-Deadline", new(spyHandler), append(test.Opts, WithReasonMetrics())...)
+			serve := func(header http.Header) {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.Header = header
+				h.ServeHTTP(httptest.NewRecorder(), req)
+			}
+			if test.Prime != nil {
+				serve(test.Prime)
+			}
+			before := make(map[string]int64)
+			for _, r := range reasons {
+				before[r.name] = rejectionCount(r.name)
+			}
+			serve(test.Header)
+			for _, r := range reasons {
+				want := before[r.name]
+				if r.name == test.Reason {
+					want++
+				}
+				if got := rejectionCount(r.name); got != want {
+					t.Errorf("after %v rejection: count for %v = %v, want %v", test.Reason, r.name, got, want)
+				}
+			}
+		})
+	}
+	for _, r := range reasons {
+		if !tested[r.name] {
+			t.Errorf("reason %v is untested", r.name)
+		}
+	}
+}
+
+func TestWithClampHistogram(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		Deadline time.Time
+
+		Reductions []time.Duration
+	}{
+		{Name: "clamped", Deadline: now.Add(time.Hour), Reductions: []time.Duration{time.Hour - time.Minute}},
+		{Name: "within-cap", Deadline: now.Add(time.Second)},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var reductions []time.Duration
+			h := FromHeader("X-MTP-Deadline", new(spyHandler),
+				WithClock(func() time.Time { return now }),
+				WithMaxDeadline(time.Minute),
+				WithClampHistogram(func(d time.Duration) { reductions = append(reductions, d) }))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-MTP-Deadline", asTimeFormat(test.Deadline))
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got, want := reductions, test.Reductions; !slices.Equal(got, want) {
+				t.Errorf("reductions = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestWithSLOObserver(t *testing.T) {
+	type observation struct {
+		route string
+		met   bool
+		over  time.Duration
+	}
+	observations := make(chan observation, 2)
+	opts := []Option{
+		WithLayout(time.RFC3339Nano),
+		WithSLOObserver(func(route string, met bool, over time.Duration) {
+			observations <- observation{route, met, over}
+		}),
+	}
+	var mux http.ServeMux
+	mux.Handle("GET /fast", FromHeader("X-MTP-Deadline", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}), opts...))
+	mux.Handle("GET /slow/{id}", FromHeader("X-MTP-Deadline", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}), opts...))
+	for _, test := range []struct {
+		Path string
+
+		Route string
+		Met   bool
+	}{
+		{Path: "/fast", Route: "GET /fast", Met: true},
+		{Path: "/slow/42", Route: "GET /slow/{id}", Met: false},
+	} {
+		req := httptest.NewRequest("GET", test.Path, nil)
+		req.Header.Set("X-MTP-Deadline", time.Now().Add(50*time.Millisecond).Format(time.RFC3339Nano))
+		mux.ServeHTTP(httptest.NewRecorder(), req)
+		got := <-observations
+		if got.route != test.Route || got.met != test.Met {
+			t.Errorf("%v: observed route %q, met %v; want %q, %v", test.Path, got.route, got.met, test.Route, test.Met)
+		}
+		if test.Met && got.over != 0 {
+			t.Errorf("%v: overBy = %v, want 0", test.Path, got.over)
+		}
+		if !test.Met && (got.over <= 0 || got.over > time.Second) {
+			t.Errorf("%v: overBy = %v, want about 50ms", test.Path, got.over)
+		}
+	}
+}
+
+func TestWithUtilizationObserver(t *testing.T) {
+	for _, test := range []struct {
+		Name   string
+		Budget time.Duration
+		Run    time.Duration
+
+		Ratio float64
+	}{
+		{Name: "half", Budget: 200 * time.Millisecond, Run: 100 * time.Millisecond, Ratio: 0.5},
+		{Name: "overrun", Budget: 50 * time.Millisecond, Run: 100 * time.Millisecond, Ratio: 2},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			ratio := math.NaN()
+			h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+				time.Sleep(test.Run)
+			}), WithLayout(time.RFC3339Nano), WithUtilizationObserver(func(_ *http.Request, r float64) { ratio = r }))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-MTP-Deadline", time.Now().Add(test.Budget).Format(time.RFC3339Nano))
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			// Allow for scheduling delays, which only lengthen the run.
+			if ratio < test.Ratio*0.9 || ratio > test.Ratio*1.5 {
+				t.Errorf("ratio = %v, want about %v", ratio, test.Ratio)
+			}
+		})
+	}
+}
+
+func TestUtilization(t *testing.T) {
+	for _, test := range []struct {
+		Elapsed, Budget time.Duration
+
+		Ratio float64
+	}{
+		{Elapsed: time.Second, Budget: 4 * time.Second, Ratio: 0.25},
+		{Elapsed: time.Second, Budget: 0, Ratio: math.Inf(1)},
+		{Elapsed: time.Second, Budget: -time.Second, Ratio: math.Inf(1)},
+	} {
+		if got, want := utilization(test.Elapsed, test.Budget), test.Ratio; got != want {
+			t.Errorf("utilization(%v, %v) = %v, want %v", test.Elapsed, test.Budget, got, want)
+		}
+	}
+}
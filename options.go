@@ -0,0 +1,694 @@
+package httpdeadline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"mime"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// An Option configures the middleware returned by [FromHeader] and
+// [FromQueryParams].
+//
+// Misconfigured options (e.g., a nil func) cause those constructors to panic,
+// as they indicate programmer error.  Use [NewPolicy] to validate options
+// without panicking.
+type Option interface {
+	apply(*config)
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(c *config) { f(c) }
+
+// ErrInvalidOption indicates that an [Option] was misconfigured.
+var ErrInvalidOption = errors.New("httpdeadline: invalid option")
+
+// invalidf returns an Option that records a configuration problem.
+func invalidf(format string, args ...any) Option {
+	err := fmt.Errorf("%w: "+format, append([]any{ErrInvalidOption}, args...)...)
+	return optionFunc(func(c *config) { c.errs = append(c.errs, err) })
+}
+
+// A Policy is a validated set of options.  It is itself an [Option] that
+// reproduces the configuration it was created from, so it can be reused across
+// handlers:
+//
+//	policy, err := httpdeadline.NewPolicy(httpdeadline.WithMaxDeadline(time.Minute))
+//	if err != nil {
+//		// Handle error.
+//	}
+//	mux.Handle("/teapotz", httpdeadline.FromHeader("X-MTP-Deadline", teapotz, policy))
+//
+// Applying a Policy replaces the configuration of any options that precede it,
+// though not their configuration problems, which are still reported; options
+// that follow it refine it further.
+type Policy struct {
+	cfg     config
+	sources []Extractor // See WithSources.
+}
+
+// NewPolicy creates a Policy from opts.  Unlike the handler constructors, which
+// panic on misconfiguration, NewPolicy reports every configuration problem
+// among opts in its error, each matching [ErrInvalidOption].  This suits
+// applications like plugin hosts that cannot tolerate panics.
+func NewPolicy(opts ...Option) (*Policy, error) {
+	cfg := newConfig(opts)
+	if err := errors.Join(cfg.errs...); err != nil {
+		return nil, err
+	}
+	return &Policy{cfg: cfg}, nil
+}
+
+func (p *Policy) apply(c *config) {
+	errs := c.errs
+	*c = p.cfg.clone()
+	c.errs = append(errs, c.errs...)
+	if p.sources != nil {
+		c.errs = append(c.errs, fmt.Errorf("%w: policy with sources applied as an option; use Policy.Handler", ErrInvalidOption))
+	}
+}
+
+type config struct {
+	errs []error
+
+	maxDeadline          func(*http.Request) time.Duration
+	maxDeadlineFixed     bool // maxDeadline ignores its request.
+	defaultDeadline      func(*http.Request) time.Duration
+	defaultFixed         bool // defaultDeadline ignores its request.
+	auditSinks           []func(AuditRecord)
+	correlationHeader    string
+	layouts              []string
+	emitLayout           string
+	tightThreshold       time.Duration
+	onTightBudget        func(*http.Request)
+	baggageMember        string
+	minServiceTime       func(*http.Request) time.Duration
+	streaming            bool
+	atDeadline           func(*http.Request)
+	formatMetrics        bool
+	multipartDeadline    bool
+	notBeforeHeader      string
+	onDeadlineFired      func(*http.Request)
+	inFlight             *InFlight
+	contentTypes         []string
+	reasonMetrics        bool
+	problemJSON          bool
+	clock                func() time.Time
+	scale                float64
+	trusted              func(*http.Request) bool
+	unboundedSentinel    string
+	defaultUntrustedOnly bool
+	relative             *relativeFormat
+	rejectExpired        bool
+	expiryLimiter        *expiryLimiter
+	adaptiveCap          func() time.Duration
+	sourceLayouts        map[Source][]string // Never mutated; see WithSourceLayout.
+	expectContinueMin    time.Duration
+	holder               *PolicyHolder
+	flushDeadline        bool
+	sloObserver          func(route string, met bool, overBy time.Duration)
+	capable              func(*http.Request) bool
+	capabilityStatus     int
+	softOffset           time.Duration
+	onSoftDeadline       func(*http.Request)
+	announceDefault      string
+	grpcWebStatus        bool
+	maxAsDefault         bool
+	zonelessAsLocal      bool
+	onFirstWrite         func(req *http.Request, sinceStart, budget time.Duration)
+	deadlineTrailer      string
+	scheduler            func(ctx context.Context, deadline time.Time, run func())
+	crossCheckTolerance  time.Duration
+	utilizationObserver  func(req *http.Request, ratio float64)
+	queryPlusLiteral     bool
+	budgetBucket         *BudgetBucket
+	deprecations         []deprecation
+	logger               *slog.Logger
+	logSampler           *logSampler
+	advisory             bool
+	clockHealthy         func() bool
+	budgetLadder         []time.Duration // Ascending.
+	reportWriter         bool
+	bypassMethods        []string
+	tunnelDeadline       time.Duration
+	receivedTimeKey      any
+}
+
+func newConfig(opts []Option) config {
+	var c config
+	for _, opt := range opts {
+		opt.apply(&c)
+	}
+	c.errs = append(c.errs, c.conflicts()...)
+	return c
+}
+
+// conflicts reports problems that arise from combinations of options rather
+// than from any one of them.
+func (c *config) conflicts() []error {
+	var errs []error
+	if c.unboundedSentinel != "" && c.trusted == nil {
+		errs = append(errs, fmt.Errorf("%w: unbounded sentinel without trusted source", ErrInvalidOption))
+	}
+	if c.defaultUntrustedOnly && c.trusted == nil {
+		errs = append(errs, fmt.Errorf("%w: default for untrusted only without trusted source", ErrInvalidOption))
+	}
+	return errs
+}
+
+// mustConfig is like newConfig but panics on misconfiguration.
+func mustConfig(opts []Option) config {
+	c := newConfig(opts)
+	if err := errors.Join(c.errs...); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// clone returns a copy of c that shares no mutable state with it.
+func (c config) clone() config {
+	c.errs = slices.Clip(c.errs)
+	c.auditSinks = slices.Clip(c.auditSinks)
+	c.layouts = slices.Clip(c.layouts)
+	c.contentTypes = slices.Clip(c.contentTypes)
+	c.deprecations = slices.Clip(c.deprecations)
+	c.bypassMethods = slices.Clip(c.bypassMethods)
+	return c
+}
+
+// WithMaxDeadline caps the deadline that a client may request at d from when
+// the request is received.  Client-provided deadlines further in the future
+// than that are clamped to it.  A non-positive d disables the cap.
+func WithMaxDeadline(d time.Duration) Option {
+	return optionFunc(func(c *config) {
+		c.maxDeadline, c.maxDeadlineFixed = func(*http.Request) time.Duration { return d }, true
+	})
+}
+
+// WithMaxDeadlineFunc is like [WithMaxDeadline] except that the cap is
+// computed per request by f, which receives the full inbound request.  This
+// permits the cap to depend on request attributes like its priority (see
+// [Urgency]) or the caller's identity.  A non-positive result from f disables
+// the cap for that request.
+func WithMaxDeadlineFunc(f func(*http.Request) time.Duration) Option {
+	if f == nil {
+		return invalidf("nil max deadline func")
+	}
+	return optionFunc(func(c *config) { c.maxDeadline, c.maxDeadlineFixed = f, false })
+}
+
+// WithAdaptiveCap is the adaptive counterpart to [WithMaxDeadline]: f is
+// consulted for every request to get the current cap, so budgets can shrink
+// while a dependency is degraded (e.g., as reported by a circuit breaker) and
+// requests fail fast rather than pile up.  f is called on every request, so
+// it must be cheap and safe for concurrent use; reading a value maintained
+// elsewhere with [sync/atomic] suits it well.  A non-positive result disables
+// the cap for that request.  When combined with [WithMaxDeadline] or
+// [WithMaxDeadlineFunc], the tighter cap wins.
+func WithAdaptiveCap(f func() time.Duration) Option {
+	if f == nil {
+		return invalidf("nil adaptive cap func")
+	}
+	return optionFunc(func(c *config) { c.adaptiveCap = f })
+}
+
+// WithDefaultDeadline applies a deadline d from when the request is received to
+// requests that do not carry a client deadline.  A client-provided value always
+// takes precedence over the default.  A non-positive d disables the default.
+func WithDefaultDeadline(d time.Duration) Option {
+	return optionFunc(func(c *config) {
+		c.defaultDeadline, c.defaultFixed = func(*http.Request) time.Duration { return d }, true
+	})
+}
+
+// WithDefaultDeadlineFunc is like [WithDefaultDeadline] except that the default
+// is computed per request by f, so it can be retuned at runtime (e.g., from a
+// configuration system) without reconstructing the handler.  f is consulted
+// only when the request carries no client deadline; a non-positive result
+// means that the request is passed through without a deadline.
+func WithDefaultDeadlineFunc(f func(*http.Request) time.Duration) Option {
+	if f == nil {
+		return invalidf("nil default deadline func")
+	}
+	return optionFunc(func(c *config) { c.defaultDeadline, c.defaultFixed = f, false })
+}
+
+// WithMaxAsDefault makes the cap from [WithMaxDeadline] or
+// [WithMaxDeadlineFunc] double as the default for requests that carry no
+// client deadline, so that no request runs longer than the cap.  Without it,
+// caps only bound client-provided deadlines.  A default set with
+// [WithDefaultDeadline] takes precedence, which allows a default tighter than
+// the cap (e.g., a 5s default for clients that do not ask with a 30s cap for
+// those that do); the cap applies whenever that default is non-positive.
+func WithMaxAsDefault() Option {
+	return optionFunc(func(c *config) { c.maxAsDefault = true })
+}
+
+// defaultFor returns the default budget for req, which carries no client
+// deadline.  It is non-positive if none applies.
+func (c *config) defaultFor(req *http.Request) time.Duration {
+	if c.defaultDeadline != nil {
+		if d := c.defaultDeadline(req); d > 0 {
+			return d
+		}
+	}
+	if c.maxAsDefault && c.maxDeadline != nil {
+		return c.maxDeadline(req)
+	}
+	return 0
+}
+
+// WithAnnounceDefault sets the named response header to the deadline applied
+// whenever it is the server's default (see [WithDefaultDeadline]) rather than
+// one the client sent, so clients can tell why their request was cut off.  The
+// deadline is formatted per [WithEmitFormat].  The header must be set before
+// the wrapped handler writes its response, so it is set before the handler
+// runs.
+func WithAnnounceDefault(name string) Option {
+	if name == "" {
+		return invalidf("empty announce default header name")
+	}
+	return optionFunc(func(c *config) { c.announceDefault = name })
+}
+
+// defaultLayouts are the layouts that [http.ParseTime] accepts.
+var defaultLayouts = []string{http.TimeFormat, time.RFC850, time.ANSIC}
+
+// parse parses val from src using the accepted layouts, reporting which one
+// matched.
+func (c *config) parse(val string, src Source) (t time.Time, layout string, err error) {
+	for _, layouts := range [...][]string{defaultLayouts, c.layouts, c.sourceLayouts[src]} {
+		for _, layout := range layouts {
+			if t, err = time.ParseInLocation(layout, val, c.zonelessLocation(layout)); err == nil {
+				return t, layout, nil
+			}
+		}
+	}
+	return time.Time{}, "", err
+}
+
+// WithLayout additionally accepts inbound deadline values in the given
+// [time.Parse] layout (e.g., [time.RFC3339]).  The formats accepted by
+// [http.ParseTime] remain accepted.  Values in layouts with numeric zone
+// offsets (e.g., "2006-01-02 15:04:05 -0700") denote the same instant whatever
+// their offset.
+func WithLayout(layout string) Option {
+	if layout == "" {
+		return invalidf("empty layout")
+	}
+	return optionFunc(func(c *config) { c.layouts = append(c.layouts, layout) })
+}
+
+// WithZonelessAsLocal interprets deadline values in formats without zone
+// information, like [time.ANSIC], as the server's local time ([time.Local])
+// rather than UTC, for clients that send local timestamps (e.g., on a LAN
+// sharing a time zone).  This deviates from [http.ParseTime], which assumes
+// UTC, so it is opt-in.  Values carrying zone information are unaffected.
+func WithZonelessAsLocal() Option {
+	return optionFunc(func(c *config) { c.zonelessAsLocal = true })
+}
+
+// zonelessLocation returns the location of values in layout without zone
+// information.
+func (c *config) zonelessLocation(layout string) *time.Location {
+	// http.TimeFormat's "GMT" is literal text rather than a zone field, but it
+	// nonetheless means UTC.
+	if c.zonelessAsLocal && layout != http.TimeFormat {
+		return time.Local
+	}
+	return time.UTC
+}
+
+// A Source identifies where a handler finds deadline values.
+type Source int
+
+// The sources of the handler constructors.  Handlers created with [From] have
+// no Source.
+const (
+	SourceHeader Source = iota + 1 // [FromHeader], [EarlyDeadline], and [PromoteDeadline]
+	SourceQuery                    // [FromQueryParams]
+	SourcePath                     // [FromPathValue]
+	SourceCookie                   // [CookieSource]
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceHeader:
+		return "header"
+	case SourceQuery:
+		return "query"
+	case SourcePath:
+		return "path"
+	case SourceCookie:
+		return "cookie"
+	default:
+		return "unknown"
+	}
+}
+
+// WithSourceLayout is like [WithLayout] except that layout is only accepted by
+// handlers reading deadline values from src.  This lets one [Policy] serve
+// several sources while keeping each to the formats its clients should use
+// (e.g., [time.RFC3339] for query parameters that browsers send) so that a
+// format is not accidentally accepted from a source that should not use it.
+// The formats accepted by [http.ParseTime] and those from [WithLayout] remain
+// accepted from every source.  Not-before values (see [WithNotBeforeHeader])
+// and the values that [PromoteDeadline] re-evaluates come from a header, so
+// they accept the layouts scoped to [SourceHeader].
+func WithSourceLayout(src Source, layout string) Option {
+	if src < SourceHeader || src > SourceCookie {
+		return invalidf("unknown source %d", src)
+	}
+	if layout == "" {
+		return invalidf("empty layout")
+	}
+	return optionFunc(func(c *config) {
+		m := make(map[Source][]string, len(c.sourceLayouts)+1)
+		for s, layouts := range c.sourceLayouts {
+			m[s] = slices.Clip(layouts)
+		}
+		m[src] = append(m[src], layout)
+		c.sourceLayouts = m
+	})
+}
+
+// WithEmitFormat sets the [time.Time.Format] layout that [Propagate] uses for
+// outbound deadlines.  It defaults to [http.TimeFormat].  The emit format is
+// independent of the formats accepted inbound (see [WithLayout]), which lets
+// one set of options translate between them at a trust boundary.
+func WithEmitFormat(layout string) Option {
+	if layout == "" {
+		return invalidf("empty emit format")
+	}
+	return optionFunc(func(c *config) { c.emitLayout = layout })
+}
+
+func (c *config) format(t time.Time) string {
+	if c.emitLayout == "" {
+		return t.UTC().Format(http.TimeFormat)
+	}
+	return t.UTC().Format(c.emitLayout)
+}
+
+// WithOnTightBudget calls fn before the wrapped handler runs whenever the
+// budget applied to a request (the time between its receipt and its deadline)
+// is under threshold.  This lets the application steer such requests toward
+// cheaper strategies.  See also [Remaining].
+func WithOnTightBudget(threshold time.Duration, fn func(*http.Request)) Option {
+	if threshold <= 0 {
+		return invalidf("non-positive tight budget threshold")
+	}
+	if fn == nil {
+		return invalidf("nil tight budget func")
+	}
+	return optionFunc(func(c *config) { c.tightThreshold, c.onTightBudget = threshold, fn })
+}
+
+// WithMinimumServiceTime rejects requests whose client-provided budget (the
+// time between the request's receipt and its deadline, after any capping) is
+// under the minimum time f reports the request needs to be served.  Such
+// requests fail fast with an error matching [ErrBudgetTooSmall] instead of
+// starting work that is doomed to miss its deadline.
+//
+// The floor may adapt to conditions: a non-positive minimum disables it for
+// that request.  For instance, f can report a positive floor only while the
+// server is measurably under load, when tight budgets are unlikely to be met,
+// and 0 while it is idle, when even tight budgets may succeed.
+func WithMinimumServiceTime(f func(*http.Request) time.Duration) Option {
+	if f == nil {
+		return invalidf("nil minimum service time func")
+	}
+	return optionFunc(func(c *config) { c.minServiceTime = f })
+}
+
+// WithStreamingMode suits long-lived responses like Server-Sent Events, where
+// cancelling the request's context at the deadline would tear down the
+// connection abruptly.  The deadline is still determined as usual and reported
+// by [Deadline], but the request's context is not cancelled when it passes.
+// Instead, atDeadline is called (on its own goroutine) when the deadline
+// passes, so the handler can send a terminal event and close the stream on its
+// own terms.  atDeadline is not called if the handler returns first.
+//
+// For SSE, have clients resend the original absolute deadline when they
+// reconnect (e.g., alongside Last-Event-ID), so the new connection continues the
+// original logical deadline rather than restarting it.
+func WithStreamingMode(atDeadline func(*http.Request)) Option {
+	if atDeadline == nil {
+		return invalidf("nil streaming deadline func")
+	}
+	return optionFunc(func(c *config) { c.streaming, c.atDeadline = true, atDeadline })
+}
+
+// WithAdvisoryOnly makes the deadline advisory, for handlers that must never
+// be cancelled on the client's behalf but may consult its deadline for their
+// own logic (e.g., to skip optional work).  The deadline is determined and
+// validated as usual, so invalid values are still rejected, and reported by
+// [Deadline] and [RequestedDeadline], but the request's context does not get
+// it: nothing is cancelled when it passes.  Options that act when the
+// deadline passes, like [WithOnDeadlineFired], [WithStreamingMode],
+// [WithMultipartReadDeadline], [WithFlushDeadlineEnforcement], and
+// [WithGRPCWebStatus], have no effect, and [CopyDeadline],
+// [DeriveBackgroundContext], and [ApplyFromValues] do not apply the deadline
+// either.
+func WithAdvisoryOnly() Option {
+	return optionFunc(func(c *config) { c.advisory = true })
+}
+
+// WithClockHealthFunc consults healthy on every request to learn whether the
+// server's clock is synchronized (e.g., per the NTP daemon).  Absolute
+// deadlines are only meaningful if the client's and server's clocks agree, so
+// while healthy reports false, absolute deadlines are ignored as if absent,
+// and defaults like [WithDefaultDeadline] apply instead.  That affects all
+// layouts (see [WithLayout]) and [FromTime] and [FromContextValue].  Relative
+// budgets do not depend on clock agreement and still apply: those of
+// [WithDurationValues] and [WithMillisecondValues], and the offsets of
+// [FromStartAndOffset], which then count from the request's receipt rather
+// than from its start time.  healthy must be cheap and safe for concurrent
+// use.
+func WithClockHealthFunc(healthy func() bool) Option {
+	if healthy == nil {
+		return invalidf("nil clock health func")
+	}
+	return optionFunc(func(c *config) { c.clockHealthy = healthy })
+}
+
+// WithBudgetLadder quantizes budgets (the time between a request's receipt and
+// its deadline, after any capping) to tiers, so that backends can be tuned for
+// a small set of budgets rather than a continuum.  Each budget is snapped down
+// to the largest tier at or below it, never up, so the server never grants
+// more than the client asked for; snapped deadlines are reported as
+// [OutcomeClamped].  Budgets under the smallest tier are rejected with an
+// error matching [ErrBudgetTooSmall].  For example, with tiers of 100ms, 500ms,
+// 1s, 5s, and 30s, a budget of 4s becomes 1s and one of 50ms is rejected.
+func WithBudgetLadder(tiers ...time.Duration) Option {
+	if len(tiers) == 0 {
+		return invalidf("empty budget ladder")
+	}
+	sorted := slices.Clone(tiers)
+	slices.Sort(sorted)
+	if sorted[0] <= 0 {
+		return invalidf("non-positive budget ladder tier %v", sorted[0])
+	}
+	sorted = slices.Compact(sorted)
+	return optionFunc(func(c *config) { c.budgetLadder = sorted })
+}
+
+// snapBudget snaps budget down to the largest of the ascending tiers at or
+// below it.  It reports false if budget is under all of them.
+func snapBudget(budget time.Duration, tiers []time.Duration) (time.Duration, bool) {
+	i, found := slices.BinarySearch(tiers, budget)
+	if found {
+		return budget, true
+	}
+	if i == 0 {
+		return 0, false
+	}
+	return tiers[i-1], true
+}
+
+// WithNotBeforeHeader names an HTTP header holding the earliest time at which
+// the request may be processed, in any accepted deadline format.  Requests
+// that arrive before it are rejected with [http.StatusTooEarly] and an error
+// matching [ErrTooEarly], which lets clients schedule work between the
+// not-before time and the deadline.  The header is optional; requests without
+// it are unaffected.
+func WithNotBeforeHeader(name string) Option {
+	if name == "" {
+		return invalidf("empty not-before header name")
+	}
+	return optionFunc(func(c *config) { c.notBeforeHeader = name })
+}
+
+// WithOnDeadlineFired calls fn (on its own goroutine) as soon as the client
+// deadline cancels a request's context, so that compensating actions or
+// metrics can be triggered exactly when the budget elapses rather than when the
+// handler notices.  fn is not called when the context is cancelled for other
+// reasons, such as client disconnection, nor once the handler has returned.
+// It has no effect in [WithStreamingMode], where the context is not
+// cancelled.
+func WithOnDeadlineFired(fn func(*http.Request)) Option {
+	if fn == nil {
+		return invalidf("nil deadline fired func")
+	}
+	return optionFunc(func(c *config) { c.onDeadlineFired = fn })
+}
+
+// WithContentTypeAllowlist restricts deadline handling to requests whose
+// Content-Type is one of the given media types (e.g., "application/json").
+// Media type parameters like charset are ignored on both sides.  Requests with
+// other or no content types pass through untouched: client deadlines are
+// neither parsed nor applied, and no default applies.
+func WithContentTypeAllowlist(types ...string) Option {
+	var mediaTypes []string
+	for _, typ := range types {
+		mediaType, _, err := mime.ParseMediaType(typ)
+		if err != nil {
+			return invalidf("content type %q: %v", typ, err)
+		}
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	return optionFunc(func(c *config) { c.contentTypes = append(c.contentTypes, mediaTypes...) })
+}
+
+// allowsContentType reports whether req's content type is eligible for
+// deadline handling.
+func (c *config) allowsContentType(req *http.Request) bool {
+	if len(c.contentTypes) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && slices.Contains(c.contentTypes, mediaType)
+}
+
+// WithClock sets the source of the current time used to decide deadlines
+// (e.g., to compute budgets, caps, and defaults).  It defaults to [time.Now];
+// tests may inject a fake clock to make decisions deterministic.  The clock
+// does not govern when contexts are actually cancelled, which follows real
+// time.
+func WithClock(now func() time.Time) Option {
+	if now == nil {
+		return invalidf("nil clock")
+	}
+	return optionFunc(func(c *config) { c.clock = now })
+}
+
+func (c *config) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock()
+}
+
+// WithDeadlineScale multiplies the budget of client-provided deadlines (the
+// time between the request's receipt and its deadline) by factor, uniformly
+// tightening (factor < 1) or loosening (factor > 1) them.  It is meant for
+// load testing near the edge without changing clients.  Scaling happens before
+// caps like [WithMaxDeadline] apply, so loosened budgets remain capped.  Scaled
+// budgets never become non-positive, and deadlines that have already passed are
+// left as-is.
+func WithDeadlineScale(factor float64) Option {
+	if !(factor > 0) || math.IsInf(factor, 0) {
+		return invalidf("deadline scale %v not positive and finite", factor)
+	}
+	return optionFunc(func(c *config) { c.scale = factor })
+}
+
+func scaleBudget(budget time.Duration, factor float64) time.Duration {
+	scaled := float64(budget) * factor
+	switch {
+	case scaled >= math.MaxInt64:
+		return math.MaxInt64
+	case scaled < 1:
+		return 1
+	default:
+		return time.Duration(scaled)
+	}
+}
+
+// WithTrustedSource restricts which callers' deadlines are honored to those for
+// which trusted reports true (e.g., callers on an internal network or bearing
+// particular credentials).  Values from other callers are silently ignored, as
+// if absent, so server defaults like [WithDefaultDeadline] apply to them
+// instead.  Without this option, all callers are trusted.
+func WithTrustedSource(trusted func(*http.Request) bool) Option {
+	if trusted == nil {
+		return invalidf("nil trusted source func")
+	}
+	return optionFunc(func(c *config) { c.trusted = trusted })
+}
+
+// WithRequireCapability requires callers to hold a capability, as reported by
+// capable (e.g., by checking for a scope in an auth token that upstream
+// middleware placed in the request's context), for their deadlines to be
+// honored.  Callers that send a deadline without it are rejected with
+// [http.StatusForbidden] (see [WithCapabilityStatus]) and an error matching
+// [ErrMissingCapability]; callers that send none pass through as usual.  This
+// is stricter than [WithTrustedSource], which silently ignores untrusted
+// callers' deadlines: it surfaces the misconfiguration or probe to the caller
+// rather than quietly substituting defaults.
+func WithRequireCapability(capable func(*http.Request) bool) Option {
+	if capable == nil {
+		return invalidf("nil capability func")
+	}
+	return optionFunc(func(c *config) { c.capable = capable })
+}
+
+// WithCapabilityStatus sets the HTTP status code for rejections matching
+// [ErrMissingCapability] (see [WithRequireCapability]).  It defaults to
+// [http.StatusForbidden]; code must be a 4xx client error.
+func WithCapabilityStatus(code int) Option {
+	if code < 400 || code > 499 {
+		return invalidf("capability status %d is not a client error", code)
+	}
+	return optionFunc(func(c *config) { c.capabilityStatus = code })
+}
+
+// WithUnboundedSentinel lets callers explicitly ask to run without a deadline
+// by sending value (e.g., "none") in place of one.  Such requests pass through
+// without a deadline, and server defaults like [WithDefaultDeadline] are
+// skipped.  Because this escapes all budgeting, it requires
+// [WithTrustedSource] and is otherwise misconfigured: sentinels from untrusted
+// callers are ignored like any of their values, so defaults apply to them.
+func WithUnboundedSentinel(value string) Option {
+	if value == "" {
+		return invalidf("empty unbounded sentinel")
+	}
+	return optionFunc(func(c *config) { c.unboundedSentinel = value })
+}
+
+// WithDefaultForUntrustedOnly restricts server defaults like
+// [WithDefaultDeadline] to callers that [WithTrustedSource] deems untrusted, so
+// that one handler can be lenient internally and safe externally:
+//
+//	          | value present       | value absent
+//	----------+---------------------+-------------
+//	trusted   | value applies       | no deadline
+//	untrusted | value ignored;      | default
+//	          | default applies     |
+//
+// Caps like [WithMaxDeadline] apply to trusted values as usual.  It requires
+// [WithTrustedSource] and is otherwise misconfigured, as all callers would be
+// trusted and no default would ever apply.
+func WithDefaultForUntrustedOnly() Option {
+	return optionFunc(func(c *config) { c.defaultUntrustedOnly = true })
+}
+
+// WithCrossCheckTolerance guards against clients whose clocks run fast, which
+// is detectable when they send both an absolute and a relative form of their
+// deadline, as with [FromStartAndOffset].  If the budget that the absolute
+// deadline implies (the time between the request's receipt and the deadline)
+// exceeds the stated relative budget by more than tolerance, the deadline is
+// clamped to the relative budget from the request's receipt and reported as
+// [OutcomeClamped].  Sources that carry only one form are unaffected.
+func WithCrossCheckTolerance(tolerance time.Duration) Option {
+	if tolerance <= 0 {
+		return invalidf("non-positive cross-check tolerance")
+	}
+	return optionFunc(func(c *config) { c.crossCheckTolerance = tolerance })
+}
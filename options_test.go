@@ -0,0 +1,1003 @@
+package httpdeadline
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithDefaultDeadlineFunc(t *testing.T) {
+	var d atomic.Int64
+	var spy spyHandler
+	h := FromHeader("X-MTP-Deadline", &spy, WithDefaultDeadlineFunc(func(*http.Request) time.Duration {
+		return time.Duration(d.Load())
+	}))
+	for _, test := range []struct {
+		Name string
+
+		Default time.Duration
+		Value   string
+
+		OK       bool
+		Deadline time.Time // Used for client values.
+	}{
+		{Name: "disabled", Default: 0, OK: false},
+		{Name: "minute", Default: time.Minute, OK: true},
+		{Name: "hour", Default: time.Hour, OK: true},
+		{Name: "client-wins", Default: time.Hour, Value: asTimeFormat(now), OK: true, Deadline: now},
+		{Name: "disabled-again", Default: -time.Second, OK: false},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			spy = spyHandler{}
+			d.Store(int64(test.Default))
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.Value != "" {
+				req.Header.Set("X-MTP-Deadline", test.Value)
+			}
+			before := time.Now()
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			after := time.Now()
+			if got, want := spy.OK, test.OK; got != want {
+				t.Fatalf("spy.OK = %v, want %v", got, want)
+			}
+			switch {
+			case !test.OK:
+			case test.Value != "":
+				if got, want := spy.Deadline, test.Deadline; !got.Equal(want) {
+					t.Errorf("spy.Deadline = %v, want %v", got, want)
+				}
+			default:
+				if got := spy.Deadline; got.Before(before.Add(test.Default)) || got.After(after.Add(test.Default)) {
+					t.Errorf("spy.Deadline = %v, want within [%v, %v]", got, before.Add(test.Default), after.Add(test.Default))
+				}
+			}
+		})
+	}
+}
+
+func TestWithLayout(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+
+		Opts  []Option
+		Value string
+
+		Status int
+	}{
+		{Name: "rfc3339-default", Value: now.Format(time.RFC3339), Status: 400},
+		{Name: "rfc3339-accepted", Opts: []Option{WithLayout(time.RFC3339)}, Value: now.Format(time.RFC3339), Status: 200},
+		{Name: "defaults-retained", Opts: []Option{WithLayout(time.RFC3339)}, Value: asANSIC(now), Status: 200},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var spy spyHandler
+			h := FromHeader("X-MTP-Deadline", &spy, test.Opts...)
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-MTP-Deadline", test.Value)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, test.Status; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+			if test.Status == 200 && !spy.Deadline.Equal(now) {
+				t.Errorf("spy.Deadline = %v, want %v", spy.Deadline, now)
+			}
+		})
+	}
+}
+
+func TestWithLayoutNumericOffsets(t *testing.T) {
+	const layout = "2006-01-02 15:04:05 -0700"
+	for _, test := range []struct {
+		Value string
+
+		Want time.Time
+	}{
+		{Value: "2024-07-22 20:10:00 +0000", Want: now},
+		{Value: "2024-07-23 01:40:00 +0530", Want: now},
+		{Value: "2024-07-22 16:10:00 -0400", Want: now},
+		{Value: "2024-07-23 08:55:00 +1245", Want: now},
+		{Value: "2024-07-22 09:10:00 -1100", Want: now},
+		{Value: "2024-07-22 20:10:00 +0530", Want: now.Add(-5*time.Hour - 30*time.Minute)},
+	} {
+		var spy spyHandler
+		h := FromHeader("X-MTP-Deadline", &spy, WithLayout(layout))
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-MTP-Deadline", test.Value)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if got, want := rec.Code, 200; got != want {
+			t.Errorf("%q: rec.Code = %v, want %v", test.Value, got, want)
+			continue
+		}
+		if got, want := spy.Deadline, test.Want; !got.Equal(want) {
+			t.Errorf("%q: spy.Deadline = %v, want %v", test.Value, got.UTC(), want)
+		}
+	}
+}
+
+func TestWithOnTightBudget(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+
+		Value string
+
+		Fired bool
+	}{
+		{Name: "absent", Value: "", Fired: false},
+		{Name: "expired", Value: asTimeFormat(now), Fired: true},
+		{Name: "tight", Value: asTimeFormat(time.Now().Add(2 * time.Second)), Fired: true},
+		{Name: "ample", Value: asTimeFormat(time.Now().Add(time.Hour)), Fired: false},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var fired bool
+			var remaining time.Duration
+			h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+				WithOnTightBudget(time.Minute, func(req *http.Request) {
+					fired = true
+					remaining, _ = Remaining(req.Context())
+				}))
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.Value != "" {
+				req.Header.Set("X-MTP-Deadline", test.Value)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got, want := fired, test.Fired; got != want {
+				t.Errorf("fired = %v, want %v", got, want)
+			}
+			if fired && remaining >= time.Minute {
+				t.Errorf("Remaining() = %v, want under %v", remaining, time.Minute)
+			}
+		})
+	}
+}
+
+func TestWithMinimumServiceTime(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+
+		Value string
+
+		Status int
+		Ran    bool
+	}{
+		{Name: "absent", Value: "", Status: 200, Ran: true},
+		{Name: "expired", Value: asTimeFormat(now), Status: 400, Ran: false},
+		{Name: "too-small", Value: asTimeFormat(time.Now().Add(2 * time.Second)), Status: 400, Ran: false},
+		{Name: "ample", Value: asTimeFormat(time.Now().Add(time.Hour)), Status: 200, Ran: true},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var ran bool
+			h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(http.ResponseWriter, *http.Request) { ran = true }),
+				WithMinimumServiceTime(func(*http.Request) time.Duration { return time.Minute }))
+			req := httptest.NewRequest("GET", "/report", nil)
+			if test.Value != "" {
+				req.Header.Set("X-MTP-Deadline", test.Value)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, test.Status; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+			if got, want := ran, test.Ran; got != want {
+				t.Errorf("handler ran = %v, want %v", got, want)
+			}
+			if !test.Ran && !strings.Contains(rec.Body.String(), ErrBudgetTooSmall.Error()) {
+				t.Errorf("rec.Body = %q, want mention of %q", rec.Body, ErrBudgetTooSmall)
+			}
+		})
+	}
+}
+
+func TestWithMinimumServiceTimeAdaptive(t *testing.T) {
+	var busy bool
+	floor := func(*http.Request) time.Duration {
+		if busy {
+			return time.Minute
+		}
+		return 0
+	}
+	for _, test := range []struct {
+		Name   string
+		Busy   bool
+		Budget time.Duration // Negative for an already-expired deadline.
+
+		Status int
+	}{
+		{Name: "idle", Busy: false, Budget: 2 * time.Second, Status: 200},
+		{Name: "busy", Busy: true, Budget: 2 * time.Second, Status: 400},
+		// Without WithRejectExpired, an expired deadline reaches the floor
+		// with a negative budget, which no floor of 0 should reject.
+		{Name: "idle-expired", Busy: false, Budget: -time.Second, Status: 200},
+		{Name: "busy-expired", Busy: true, Budget: -time.Second, Status: 400},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			busy = test.Busy
+			h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+				WithMinimumServiceTime(floor))
+			req := httptest.NewRequest("GET", "/report", nil)
+			req.Header.Set("X-MTP-Deadline", asTimeFormat(time.Now().Add(test.Budget)))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, test.Status; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		policy, err := NewPolicy(WithMaxDeadline(time.Minute), WithLayout(time.RFC3339))
+		if err != nil {
+			t.Fatalf("NewPolicy() = _, %v; want nil error", err)
+		}
+		var spy spyHandler
+		h := FromHeader("X-MTP-Deadline", &spy, policy)
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-MTP-Deadline", time.Now().Add(time.Hour).Format(time.RFC3339))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if !spy.OK || spy.Deadline.After(time.Now().Add(time.Minute)) {
+			t.Errorf("spy.Deadline = %v, %v; want clamped to a minute", spy.Deadline, spy.OK)
+		}
+	})
+	t.Run("invalid", func(t *testing.T) {
+		policy, err := NewPolicy(
+			WithMaxDeadlineFunc(nil),
+			WithLayout(time.RFC3339),
+			WithLayout(""),
+			WithOnTightBudget(-time.Second, func(*http.Request) {}),
+		)
+		if policy != nil {
+			t.Errorf("NewPolicy() = %v, _; want nil", policy)
+		}
+		if !errors.Is(err, ErrInvalidOption) {
+			t.Fatalf("NewPolicy() = _, %v; want %v", err, ErrInvalidOption)
+		}
+		for _, want := range []string{"max deadline", "layout", "threshold"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("NewPolicy() error %q does not mention %q", err, want)
+			}
+		}
+		var joined interface{ Unwrap() []error }
+		if !errors.As(err, &joined) || len(joined.Unwrap()) != 3 {
+			t.Errorf("NewPolicy() error %q does not report three problems", err)
+		}
+	})
+	t.Run("sugar-panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("FromHeader() did not panic on invalid option")
+			}
+		}()
+		FromHeader("X-MTP-Deadline", new(spyHandler), WithLayout(""))
+	})
+	t.Run("invalid-before-policy", func(t *testing.T) {
+		policy, err := NewPolicy(WithLayout(time.RFC3339))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewPolicy(WithClock(nil), policy); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("NewPolicy(WithClock(nil), policy) = _, %v; want %v", err, ErrInvalidOption)
+		}
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("FromHeader() did not panic on invalid option before policy")
+			}
+		}()
+		FromHeader("X-MTP-Deadline", new(spyHandler), WithLayout(""), policy)
+	})
+	t.Run("isolated", func(t *testing.T) {
+		policy, err := NewPolicy(WithLayout(time.RFC3339))
+		if err != nil {
+			t.Fatal(err)
+		}
+		FromHeader("X-MTP-Deadline", new(spyHandler), policy, WithLayout(time.Kitchen))
+		if got := policy.cfg.layouts; len(got) != 1 {
+			t.Errorf("policy layouts = %q after reuse, want unchanged", got)
+		}
+	})
+}
+
+func TestWithStreamingMode(t *testing.T) {
+	t.Run("deadline-fires", func(t *testing.T) {
+		fired := make(chan struct{})
+		var ctxErr error
+		var deadline time.Time
+		var ok bool
+		h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			select {
+			case <-fired:
+			case <-time.After(5 * time.Second):
+				t.Error("streaming deadline func did not fire")
+			}
+			ctxErr = req.Context().Err()
+			deadline, ok = Deadline(req.Context())
+		}), WithLayout(time.RFC3339Nano), WithStreamingMode(func(*http.Request) { close(fired) }))
+		req := httptest.NewRequest("GET", "/events", nil)
+		want := time.Now().Add(50 * time.Millisecond)
+		req.Header.Set("X-MTP-Deadline", want.Format(time.RFC3339Nano))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if ctxErr != nil {
+			t.Errorf("req.Context().Err() = %v, want nil", ctxErr)
+		}
+		if !ok || !deadline.Equal(want) {
+			t.Errorf("Deadline() = %v, %v; want %v, true", deadline, ok, want)
+		}
+	})
+	t.Run("real-time", func(t *testing.T) {
+		// The timer follows real time, not the injected clock, which lags it.
+		fired := make(chan struct{})
+		h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			select {
+			case <-fired:
+			case <-time.After(5 * time.Second):
+				t.Error("streaming deadline func did not fire")
+			}
+		}), WithLayout(time.RFC3339Nano), WithClock(func() time.Time { return now }), WithStreamingMode(func(*http.Request) { close(fired) }))
+		req := httptest.NewRequest("GET", "/events", nil)
+		req.Header.Set("X-MTP-Deadline", time.Now().Add(50*time.Millisecond).Format(time.RFC3339Nano))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	})
+	t.Run("handler-finishes-first", func(t *testing.T) {
+		var fired atomic.Bool
+		h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+			WithLayout(time.RFC3339Nano), WithStreamingMode(func(*http.Request) { fired.Store(true) }))
+		req := httptest.NewRequest("GET", "/events", nil)
+		req.Header.Set("X-MTP-Deadline", time.Now().Add(50*time.Millisecond).Format(time.RFC3339Nano))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		time.Sleep(100 * time.Millisecond)
+		if fired.Load() {
+			t.Error("streaming deadline func fired after handler returned")
+		}
+	})
+}
+
+func TestWithNotBeforeHeader(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+
+		NotBefore string
+
+		Status int
+	}{
+		{Name: "absent", NotBefore: "", Status: 200},
+		{Name: "on-time", NotBefore: asTimeFormat(now), Status: 200},
+		{Name: "early", NotBefore: asTimeFormat(time.Now().Add(time.Hour)), Status: 425},
+		{Name: "malformed", NotBefore: "garbage", Status: 400},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var spy spyHandler
+			h := FromHeader("X-MTP-Deadline", &spy, WithNotBeforeHeader("X-MTP-Not-Before"))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-MTP-Deadline", asTimeFormat(time.Now().Add(2*time.Hour)))
+			if test.NotBefore != "" {
+				req.Header.Set("X-MTP-Not-Before", test.NotBefore)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, test.Status; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+			if got, want := spy.OK, test.Status == 200; got != want {
+				t.Errorf("spy.OK = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestWithOnDeadlineFired(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+
+		Budget time.Duration
+		Work   time.Duration
+
+		Fired bool
+	}{
+		{Name: "deadline-fires", Budget: 20 * time.Millisecond, Work: 200 * time.Millisecond, Fired: true},
+		{Name: "completes", Budget: 100 * time.Millisecond, Work: 0, Fired: false},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			fired := make(chan error, 1)
+			h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+				select {
+				case <-time.After(test.Work):
+				case <-req.Context().Done():
+				}
+			}), WithLayout(time.RFC3339Nano), WithOnDeadlineFired(func(req *http.Request) {
+				fired <- context.Cause(req.Context())
+			}))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-MTP-Deadline", time.Now().Add(test.Budget).Format(time.RFC3339Nano))
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			select {
+			case cause := <-fired:
+				if !test.Fired {
+					t.Errorf("callback fired with cause %v, want no call", cause)
+				}
+				if !errors.Is(cause, ErrDeadlineExceeded) {
+					t.Errorf("context.Cause() = %v, want %v", cause, ErrDeadlineExceeded)
+				}
+			case <-time.After(2 * test.Budget):
+				if test.Fired {
+					t.Error("callback not fired")
+				}
+			}
+		})
+	}
+	t.Run("client-cancels", func(t *testing.T) {
+		var fired atomic.Bool
+		h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+			<-req.Context().Done()
+			time.Sleep(10 * time.Millisecond)
+		}), WithOnDeadlineFired(func(*http.Request) { fired.Store(true) }))
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+		req.Header.Set("X-MTP-Deadline", asTimeFormat(time.Now().Add(time.Hour)))
+		time.AfterFunc(10*time.Millisecond, cancel)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if fired.Load() {
+			t.Error("callback fired on client cancellation")
+		}
+	})
+}
+
+func TestWithContentTypeAllowlist(t *testing.T) {
+	for _, test := range []struct {
+		ContentType string
+
+		Applied bool
+	}{
+		{ContentType: "application/json", Applied: true},
+		{ContentType: "application/json; charset=utf-8", Applied: true},
+		{ContentType: "Application/JSON", Applied: true},
+		{ContentType: "application/problem+json", Applied: false},
+		{ContentType: "multipart/form-data; boundary=xyz", Applied: false},
+		{ContentType: "", Applied: false},
+	} {
+		var spy spyHandler
+		h := FromHeader("X-MTP-Deadline", &spy,
+			WithContentTypeAllowlist("application/json", "application/grpc-web+json"),
+			WithDefaultDeadline(time.Hour))
+		req := httptest.NewRequest("POST", "/", nil)
+		req.Header.Set("X-MTP-Deadline", "garbage")
+		if test.ContentType != "" {
+			req.Header.Set("Content-Type", test.ContentType)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if test.Applied {
+			if got, want := rec.Code, 400; got != want {
+				t.Errorf("Content-Type %q: rec.Code = %v, want %v", test.ContentType, got, want)
+			}
+			continue
+		}
+		if got, want := rec.Code, 200; got != want {
+			t.Errorf("Content-Type %q: rec.Code = %v, want %v", test.ContentType, got, want)
+		}
+		if spy.OK {
+			t.Errorf("Content-Type %q: deadline %v applied, want pass through", test.ContentType, spy.Deadline)
+		}
+	}
+	if _, err := NewPolicy(WithContentTypeAllowlist("application/json; garbage")); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("NewPolicy() with malformed content type = %v, want %v", err, ErrInvalidOption)
+	}
+}
+
+func TestWithMaxDeadlineFuncSLATiers(t *testing.T) {
+	tiers := map[string]time.Duration{
+		"key-gold":   time.Minute,
+		"key-silver": 10 * time.Second,
+	}
+	const fallback = 2 * time.Second
+	capByKey := func(req *http.Request) time.Duration {
+		if d, ok := tiers[req.Header.Get("X-API-Key")]; ok {
+			return d
+		}
+		return fallback
+	}
+	for _, test := range []struct {
+		Key string
+
+		Cap time.Duration
+	}{
+		{Key: "key-gold", Cap: time.Minute},
+		{Key: "key-silver", Cap: 10 * time.Second},
+		{Key: "key-unknown", Cap: fallback},
+		{Key: "", Cap: fallback},
+	} {
+		var spy spyHandler
+		h := FromHeader("X-MTP-Deadline", &spy, WithMaxDeadlineFunc(capByKey))
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-MTP-Deadline", asTimeFormat(time.Now().Add(time.Hour)))
+		if test.Key != "" {
+			req.Header.Set("X-API-Key", test.Key)
+		}
+		before := time.Now()
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		after := time.Now()
+		if low, high := before.Add(test.Cap), after.Add(test.Cap); !spy.OK || spy.Deadline.Before(low) || spy.Deadline.After(high) {
+			t.Errorf("key %q: spy.Deadline = %v, %v; want within [%v, %v]", test.Key, spy.Deadline, spy.OK, low, high)
+		}
+	}
+}
+
+func TestWithDeadlineScale(t *testing.T) {
+	clock := func() time.Time { return now }
+	for _, test := range []struct {
+		Name string
+
+		Factor   float64
+		Max      time.Duration
+		Deadline time.Time
+
+		Want    time.Time
+		Outcome Outcome
+	}{
+		{Name: "halved", Factor: 0.5, Deadline: now.Add(time.Minute), Want: now.Add(30 * time.Second), Outcome: OutcomeClamped},
+		{Name: "doubled", Factor: 2, Deadline: now.Add(time.Minute), Want: now.Add(2 * time.Minute), Outcome: OutcomeApplied},
+		{Name: "doubled-capped", Factor: 2, Max: 90 * time.Second, Deadline: now.Add(time.Minute), Want: now.Add(90 * time.Second), Outcome: OutcomeClamped},
+		{Name: "tiny", Factor: 1e-12, Deadline: now.Add(time.Second), Want: now.Add(time.Nanosecond), Outcome: OutcomeClamped},
+		{Name: "expired", Factor: 0.5, Deadline: now.Add(-time.Minute), Want: now.Add(-time.Minute), Outcome: OutcomeApplied},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var got AuditRecord
+			h := FromHeader("X-MTP-Deadline", new(spyHandler),
+				WithClock(clock),
+				WithDeadlineScale(test.Factor),
+				WithMaxDeadline(test.Max),
+				WithAuditSink(func(rec AuditRecord) { got = rec }))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-MTP-Deadline", asTimeFormat(test.Deadline))
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if !got.Effective.Equal(test.Want) {
+				t.Errorf("rec.Effective = %v, want %v", got.Effective, test.Want)
+			}
+			if got.Outcome != test.Outcome {
+				t.Errorf("rec.Outcome = %v, want %v", got.Outcome, test.Outcome)
+			}
+		})
+	}
+	for _, factor := range []float64{0, -1, math.Inf(1), math.NaN()} {
+		if _, err := NewPolicy(WithDeadlineScale(factor)); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("NewPolicy(WithDeadlineScale(%v)) = %v, want %v", factor, err, ErrInvalidOption)
+		}
+	}
+}
+
+func TestWithUnboundedSentinel(t *testing.T) {
+	trusted := func(req *http.Request) bool { return req.Header.Get("Authorization") == "Bearer internal" }
+	for _, test := range []struct {
+		Name string
+
+		Trusted bool
+		Value   string
+
+		Outcome Outcome
+		OK      bool
+	}{
+		{Name: "trusted-sentinel", Trusted: true, Value: "none", Outcome: OutcomeUnbounded, OK: false},
+		{Name: "untrusted-sentinel", Trusted: false, Value: "none", Outcome: OutcomeDefault, OK: true},
+		{Name: "trusted-value", Trusted: true, Value: asTimeFormat(now), Outcome: OutcomeApplied, OK: true},
+		{Name: "untrusted-value", Trusted: false, Value: asTimeFormat(now), Outcome: OutcomeDefault, OK: true},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var (
+				spy spyHandler
+				got AuditRecord
+			)
+			h := FromHeader("X-MTP-Deadline", &spy,
+				WithTrustedSource(trusted),
+				WithUnboundedSentinel("none"),
+				WithDefaultDeadline(time.Minute),
+				WithAuditSink(func(rec AuditRecord) { got = rec }))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-MTP-Deadline", test.Value)
+			if test.Trusted {
+				req.Header.Set("Authorization", "Bearer internal")
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, 200; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+			if got, want := got.Outcome, test.Outcome; got != want {
+				t.Errorf("rec.Outcome = %v, want %v", got, want)
+			}
+			if got, want := spy.OK, test.OK; got != want {
+				t.Errorf("spy.OK = %v, want %v", got, want)
+			}
+		})
+	}
+	if _, err := NewPolicy(WithUnboundedSentinel("none")); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("NewPolicy(WithUnboundedSentinel(\"none\")) = %v, want %v", err, ErrInvalidOption)
+	}
+}
+
+func TestWithDefaultForUntrustedOnly(t *testing.T) {
+	clock := func() time.Time { return now }
+	trusted := func(req *http.Request) bool { return req.Header.Get("Authorization") == "Bearer internal" }
+	for _, test := range []struct {
+		Name string
+
+		Trusted bool
+		Value   bool
+
+		Outcome  Outcome
+		Deadline time.Time
+	}{
+		{Name: "trusted-value", Trusted: true, Value: true, Outcome: OutcomeApplied, Deadline: now.Add(time.Hour)},
+		{Name: "trusted-absent", Trusted: true, Value: false, Outcome: OutcomeAbsent},
+		{Name: "untrusted-value", Trusted: false, Value: true, Outcome: OutcomeDefault, Deadline: now.Add(time.Second)},
+		{Name: "untrusted-absent", Trusted: false, Value: false, Outcome: OutcomeDefault, Deadline: now.Add(time.Second)},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var (
+				spy spyHandler
+				got AuditRecord
+			)
+			h := FromHeader("X-MTP-Deadline", &spy,
+				WithClock(clock),
+				WithTrustedSource(trusted),
+				WithDefaultDeadline(time.Second),
+				WithDefaultForUntrustedOnly(),
+				WithAuditSink(func(rec AuditRecord) { got = rec }))
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.Value {
+				req.Header.Set("X-MTP-Deadline", asTimeFormat(now.Add(time.Hour)))
+			}
+			if test.Trusted {
+				req.Header.Set("Authorization", "Bearer internal")
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got, want := got.Outcome, test.Outcome; got != want {
+				t.Errorf("rec.Outcome = %v, want %v", got, want)
+			}
+			if got, want := spy.OK, !test.Deadline.IsZero(); got != want {
+				t.Fatalf("spy.OK = %v, want %v", got, want)
+			}
+			if got, want := spy.Deadline, test.Deadline; spy.OK && !got.Equal(want) {
+				t.Errorf("spy.Deadline = %v, want %v", got, want)
+			}
+		})
+	}
+	if _, err := NewPolicy(WithDefaultDeadline(time.Second), WithDefaultForUntrustedOnly()); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("NewPolicy(WithDefaultForUntrustedOnly()) = %v, want %v", err, ErrInvalidOption)
+	}
+}
+
+func TestWithAdaptiveCap(t *testing.T) {
+	var (
+		budget atomic.Int64
+		spy    spyHandler
+	)
+	h := FromHeader("X-MTP-Deadline", &spy,
+		WithClock(func() time.Time { return now }),
+		WithMaxDeadline(time.Minute),
+		WithAdaptiveCap(func() time.Duration { return time.Duration(budget.Load()) }))
+	for _, test := range []struct {
+		Cap time.Duration
+
+		Deadline time.Time
+	}{
+		{Cap: 30 * time.Second, Deadline: now.Add(30 * time.Second)},
+		{Cap: 5 * time.Second, Deadline: now.Add(5 * time.Second)},
+		{Cap: 0, Deadline: now.Add(time.Minute)},
+		{Cap: 10 * time.Minute, Deadline: now.Add(time.Minute)},
+	} {
+		budget.Store(int64(test.Cap))
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-MTP-Deadline", asTimeFormat(now.Add(time.Hour)))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if got, want := spy.Deadline, test.Deadline; !spy.OK || !got.Equal(want) {
+			t.Errorf("cap %v: spy.Deadline = %v (%v), want %v", test.Cap, got, spy.OK, want)
+		}
+	}
+}
+
+func TestWithSourceLayout(t *testing.T) {
+	p, err := NewPolicy(
+		WithSourceLayout(SourceHeader, time.RFC1123Z),
+		WithSourceLayout(SourceQuery, time.RFC3339))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Hour).Truncate(time.Second)
+	for _, test := range []struct {
+		Name   string
+		Source Source
+		Layout string
+
+		Code int
+	}{
+		{Name: "header-own", Source: SourceHeader, Layout: time.RFC1123Z, Code: 200},
+		{Name: "header-query-only", Source: SourceHeader, Layout: time.RFC3339, Code: 400},
+		{Name: "header-default", Source: SourceHeader, Layout: http.TimeFormat, Code: 200},
+		{Name: "query-own", Source: SourceQuery, Layout: time.RFC3339, Code: 200},
+		{Name: "query-header-only", Source: SourceQuery, Layout: time.RFC1123Z, Code: 400},
+		{Name: "query-default", Source: SourceQuery, Layout: http.TimeFormat, Code: 200},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			val := deadline.UTC().Format(test.Layout)
+			var h http.Handler
+			req := httptest.NewRequest("GET", "/", nil)
+			switch test.Source {
+			case SourceHeader:
+				h = FromHeader("X-MTP-Deadline", new(spyHandler), p)
+				req.Header.Set("X-MTP-Deadline", val)
+			case SourceQuery:
+				h = FromQueryParams("deadline", new(spyHandler), p)
+				req.URL.RawQuery = url.Values{"deadline": {val}}.Encode()
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, test.Code; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+		})
+	}
+	if _, err := NewPolicy(WithSourceLayout(Source(0), time.RFC3339)); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("NewPolicy(WithSourceLayout(Source(0), ...)) = %v, want %v", err, ErrInvalidOption)
+	}
+}
+
+func TestWithRequireCapability(t *testing.T) {
+	capable := func(req *http.Request) bool { return req.Header.Get("X-Scope") == "deadline:set" }
+	for _, test := range []struct {
+		Name    string
+		Capable bool
+		Value   bool
+		Opts    []Option
+
+		Code int
+		OK   bool
+	}{
+		{Name: "capable-value", Capable: true, Value: true, Code: 200, OK: true},
+		{Name: "incapable-value", Capable: false, Value: true, Code: 403},
+		{Name: "incapable-value-custom-status", Capable: false, Value: true, Opts: []Option{WithCapabilityStatus(401)}, Code: 401},
+		{Name: "incapable-absent", Capable: false, Value: false, Code: 200, OK: false},
+		{Name: "capable-absent", Capable: true, Value: false, Code: 200, OK: false},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var spy spyHandler
+			h := FromHeader("X-MTP-Deadline", &spy, append(test.Opts, WithRequireCapability(capable))...)
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.Value {
+				req.Header.Set("X-MTP-Deadline", asTimeFormat(time.Now().Add(time.Hour)))
+			}
+			if test.Capable {
+				req.Header.Set("X-Scope", "deadline:set")
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, test.Code; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+			if got, want := spy.OK, test.OK; got != want {
+				t.Errorf("spy.OK = %v, want %v", got, want)
+			}
+		})
+	}
+	if _, err := NewPolicy(WithCapabilityStatus(500)); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("NewPolicy(WithCapabilityStatus(500)) = %v, want %v", err, ErrInvalidOption)
+	}
+}
+
+func TestWithAnnounceDefault(t *testing.T) {
+	for _, test := range []struct {
+		Name  string
+		Value *string
+
+		Announced string
+	}{
+		{Name: "client-value", Value: ptr(asTimeFormat(now.Add(time.Minute)))},
+		{Name: "default", Announced: asTimeFormat(now.Add(time.Second))},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			h := FromHeader("X-MTP-Deadline", new(spyHandler),
+				WithClock(func() time.Time { return now }),
+				WithDefaultDeadline(time.Second),
+				WithAnnounceDefault("X-MTP-Deadline-Default"))
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.Value != nil {
+				req.Header.Set("X-MTP-Deadline", *test.Value)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			got, ok := rec.Result().Header["X-Mtp-Deadline-Default"]
+			if want := test.Announced != ""; ok != want {
+				t.Fatalf("X-MTP-Deadline-Default present = %v, want %v", ok, want)
+			}
+			if ok && got[0] != test.Announced {
+				t.Errorf("X-MTP-Deadline-Default = %q, want %q", got[0], test.Announced)
+			}
+		})
+	}
+}
+
+func TestWithMaxAsDefault(t *testing.T) {
+	for _, test := range []struct {
+		Name  string
+		Value bool
+		Opts  []Option
+
+		Deadline time.Time
+	}{
+		{Name: "value-clamped", Value: true, Opts: []Option{WithMaxAsDefault()}, Deadline: now.Add(time.Minute)},
+		{Name: "absent-cap-applied", Opts: []Option{WithMaxAsDefault()}, Deadline: now.Add(time.Minute)},
+		{Name: "absent-without", Opts: nil},
+		{Name: "absent-default-precedes", Opts: []Option{WithMaxAsDefault(), WithDefaultDeadline(5 * time.Second)}, Deadline: now.Add(5 * time.Second)},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var spy spyHandler
+			opts := append([]Option{WithClock(func() time.Time { return now }), WithMaxDeadline(time.Minute)}, test.Opts...)
+			h := FromHeader("X-MTP-Deadline", &spy, opts...)
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.Value {
+				req.Header.Set("X-MTP-Deadline", asTimeFormat(now.Add(time.Hour)))
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got, want := spy.OK, !test.Deadline.IsZero(); got != want {
+				t.Fatalf("spy.OK = %v, want %v", got, want)
+			}
+			if got, want := spy.Deadline, test.Deadline; spy.OK && !got.Equal(want) {
+				t.Errorf("spy.Deadline = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestWithZonelessAsLocal(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("UTC-5", -5*60*60)
+	t.Cleanup(func() { time.Local = local })
+
+	for _, test := range []struct {
+		Name  string
+		Opts  []Option
+		Value string
+
+		Deadline time.Time
+	}{
+		{Name: "ansic-default-utc", Value: asANSIC(now), Deadline: now},
+		{Name: "ansic-local", Opts: []Option{WithZonelessAsLocal()}, Value: asANSIC(now), Deadline: now.Add(5 * time.Hour)},
+		{Name: "timeformat-local-unaffected", Opts: []Option{WithZonelessAsLocal()}, Value: asTimeFormat(now), Deadline: now},
+		{Name: "rfc850-local-unaffected", Opts: []Option{WithZonelessAsLocal()}, Value: asRFC850(now), Deadline: now},
+		{Name: "custom-local", Opts: []Option{WithZonelessAsLocal(), WithLayout(time.DateTime)}, Value: now.Format(time.DateTime), Deadline: now.Add(5 * time.Hour)},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var spy spyHandler
+			h := FromHeader("X-MTP-Deadline", &spy, test.Opts...)
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-MTP-Deadline", test.Value)
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got, want := spy.Deadline, test.Deadline; !spy.OK || !got.Equal(want) {
+				t.Errorf("spy.Deadline = %v (%v), want %v", got.UTC(), spy.OK, want)
+			}
+		})
+	}
+}
+
+func TestWithClockHealthFunc(t *testing.T) {
+	var (
+		healthy bool
+		got     AuditRecord
+	)
+	opts := []Option{
+		WithClock(func() time.Time { return now }),
+		WithDefaultDeadline(10 * time.Second),
+		WithClockHealthFunc(func() bool { return healthy }),
+		WithAuditSink(func(rec AuditRecord) { got = rec }),
+	}
+	var spy spyHandler
+	absolute := FromHeader("X-MTP-Deadline", &spy, opts...)
+	relative := FromHeader("X-MTP-Budget", &spy, append(opts, WithDurationValues())...)
+	startAndOffset := FromStartAndOffset("X-Edge-Start", "X-Edge-Budget", &spy, opts...)
+	for _, test := range []struct {
+		Name    string
+		Handler http.Handler
+		Headers map[string]string
+		Healthy bool
+
+		Deadline time.Time
+		Outcome  Outcome
+	}{
+		{Name: "absolute-healthy", Handler: absolute, Headers: map[string]string{"X-MTP-Deadline": asTimeFormat(now.Add(time.Minute))}, Healthy: true, Deadline: now.Add(time.Minute), Outcome: OutcomeApplied},
+		{Name: "absolute-unhealthy", Handler: absolute, Headers: map[string]string{"X-MTP-Deadline": asTimeFormat(now.Add(time.Minute))}, Healthy: false, Deadline: now.Add(10 * time.Second), Outcome: OutcomeDefault},
+		{Name: "relative-healthy", Handler: relative, Headers: map[string]string{"X-MTP-Budget": "30s"}, Healthy: true, Deadline: now.Add(30 * time.Second), Outcome: OutcomeApplied},
+		{Name: "relative-unhealthy", Handler: relative, Headers: map[string]string{"X-MTP-Budget": "30s"}, Healthy: false, Deadline: now.Add(30 * time.Second), Outcome: OutcomeApplied},
+		{Name: "start-and-offset-healthy", Handler: startAndOffset, Headers: map[string]string{"X-Edge-Start": asTimeFormat(now.Add(-5 * time.Second)), "X-Edge-Budget": "30s"}, Healthy: true, Deadline: now.Add(25 * time.Second), Outcome: OutcomeApplied},
+		{Name: "start-and-offset-unhealthy", Handler: startAndOffset, Headers: map[string]string{"X-Edge-Start": asTimeFormat(now.Add(-5 * time.Second)), "X-Edge-Budget": "30s"}, Healthy: false, Deadline: now.Add(30 * time.Second), Outcome: OutcomeApplied},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			healthy = test.Healthy
+			spy = spyHandler{}
+			req := httptest.NewRequest("GET", "/", nil)
+			for k, v := range test.Headers {
+				req.Header.Set(k, v)
+			}
+			test.Handler.ServeHTTP(httptest.NewRecorder(), req)
+			if got, want := spy.Deadline, test.Deadline; !got.Equal(want) {
+				t.Errorf("spy.Deadline = %v, want %v", got, want)
+			}
+			if got, want := got.Outcome, test.Outcome; got != want {
+				t.Errorf("rec.Outcome = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestWithBudgetLadder(t *testing.T) {
+	tiers := []time.Duration{30 * time.Second, 100 * time.Millisecond, time.Second, 500 * time.Millisecond, 5 * time.Second}
+	for _, test := range []struct {
+		Budget time.Duration
+
+		Status  int
+		Snapped time.Duration
+		Outcome Outcome
+	}{
+		{Budget: 50 * time.Millisecond, Status: 400, Outcome: OutcomeRejected},
+		{Budget: 100 * time.Millisecond, Status: 200, Snapped: 100 * time.Millisecond, Outcome: OutcomeApplied},
+		{Budget: 499 * time.Millisecond, Status: 200, Snapped: 100 * time.Millisecond, Outcome: OutcomeClamped},
+		{Budget: 4 * time.Second, Status: 200, Snapped: time.Second, Outcome: OutcomeClamped},
+		{Budget: 5 * time.Second, Status: 200, Snapped: 5 * time.Second, Outcome: OutcomeApplied},
+		{Budget: time.Hour, Status: 200, Snapped: 30 * time.Second, Outcome: OutcomeClamped},
+	} {
+		t.Run(test.Budget.String(), func(t *testing.T) {
+			var (
+				spy spyHandler
+				got AuditRecord
+			)
+			h := FromHeader("X-MTP-Deadline", &spy,
+				WithClock(func() time.Time { return now }),
+				WithLayout(time.RFC3339Nano),
+				WithBudgetLadder(tiers...),
+				WithAuditSink(func(rec AuditRecord) { got = rec }))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-MTP-Deadline", now.Add(test.Budget).Format(time.RFC3339Nano))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, test.Status; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+			if got, want := got.Outcome, test.Outcome; got != want {
+				t.Errorf("rec.Outcome = %v, want %v", got, want)
+			}
+			if test.Status != 200 {
+				if !errors.Is(got.Err, ErrBudgetTooSmall) {
+					t.Errorf("rec.Err = %v, want %v", got.Err, ErrBudgetTooSmall)
+				}
+				return
+			}
+			if got, want := spy.Deadline, now.Add(test.Snapped); !got.Equal(want) {
+				t.Errorf("spy.Deadline = %v, want %v", got, want)
+			}
+		})
+	}
+	for _, tiers := range [][]time.Duration{nil, {0, time.Second}, {-time.Second}} {
+		if _, err := NewPolicy(WithBudgetLadder(tiers...)); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("NewPolicy(WithBudgetLadder(%v)) = %v, want %v", tiers, err, ErrInvalidOption)
+		}
+	}
+}
+
+func TestSourceString(t *testing.T) {
+	for s, want := range map[Source]string{
+		SourceHeader: "header",
+		SourceQuery:  "query",
+		SourcePath:   "path",
+		SourceCookie: "cookie",
+		Source(0):    "unknown",
+	} {
+		if got := s.String(); got != want {
+			t.Errorf("Source(%d).String() = %q, want %q", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package httpdeadline
+
+import "net/http"
+
+// EarlyDeadline and PromoteDeadline split deadline handling into two phases
+// around middleware that establishes trust in the caller, like authentication.
+//
+// Mount EarlyDeadline as the outermost handler so that everything, including
+// expensive authentication, is bounded by the client's budget.  At that point
+// the caller is untrusted, so configure it conservatively (e.g., with a tight
+// [WithMaxDeadline]).  Mount PromoteDeadline inside the authentication
+// middleware; it re-evaluates the same client value with opts that may now
+// depend on the established trust (e.g., a [WithMaxDeadlineFunc] keyed on the
+// authenticated caller).  Because contexts only ever tighten, promotion either
+// confirms the provisional deadline or tightens it; it never extends it.
+//
+//	h := httpdeadline.EarlyDeadline("X-MTP-Deadline",
+//		auth(httpdeadline.PromoteDeadline(teapotz, httpdeadline.WithMaxDeadlineFunc(capForCaller))),
+//		httpdeadline.WithMaxDeadline(time.Minute))
+//
+// EarlyDeadline otherwise behaves like [FromHeader].
+func EarlyDeadline(name string, h http.Handler, opts ...Option) http.Handler {
+	early := FromHeader(name, h, opts...).(*handler)
+	early.provisional = true
+	return early
+}
+
+// PromoteDeadline re-evaluates the provisional deadline that [EarlyDeadline]
+// applied further out with opts, which should accept the same formats.  It
+// passes requests through that carry no provisional deadline.  [Deadline]
+// subsequently reports the promoted deadline.  The value comes from
+// EarlyDeadline's header, so layouts scoped with [WithSourceLayout] apply to
+// it as they do to [SourceHeader].
+func PromoteDeadline(h http.Handler, opts ...Option) http.Handler {
+	promote := From(func(req *http.Request) (string, bool, error) {
+		a, ok := appliedFrom(req.Context())
+		if !ok || !a.provisional {
+			return "", false, nil
+		}
+		return a.value, true, nil
+	}, h, opts...).(*handler)
+	promote.source, promote.promote = SourceHeader, true
+	return promote
+}
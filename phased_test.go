@@ -0,0 +1,109 @@
+package httpdeadline
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type trustedKey struct{}
+
+func TestPromoteDeadline(t *testing.T) {
+	// auth marks requests bearing the right token as trusted.
+	auth := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			trusted := req.Header.Get("Authorization") == "Bearer trusted"
+			h.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), trustedKey{}, trusted)))
+		})
+	}
+	// Untrusted callers are held to ten seconds once authenticated.
+	capForCaller := func(req *http.Request) time.Duration {
+		if trusted, _ := req.Context().Value(trustedKey{}).(bool); trusted {
+			return 0
+		}
+		return 10 * time.Second
+	}
+	var (
+		spy      spyHandler
+		reported time.Time
+	)
+	inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		spy.ServeHTTP(w, req)
+		reported, _ = Deadline(req.Context())
+	})
+	h := EarlyDeadline("X-MTP-Deadline",
+		auth(PromoteDeadline(inner, WithMaxDeadlineFunc(capForCaller))),
+		WithMaxDeadline(time.Hour))
+
+	for _, test := range []struct {
+		Name string
+
+		Token string
+		Value string
+
+		Within time.Duration
+		Exact  time.Time
+	}{
+		{Name: "trusted-confirmed", Token: "Bearer trusted", Value: asTimeFormat(now), Exact: now},
+		{Name: "trusted-early-cap", Token: "Bearer trusted", Value: asTimeFormat(time.Now().Add(2 * time.Hour)), Within: time.Hour},
+		{Name: "untrusted-tightened", Value: asTimeFormat(time.Now().Add(30 * time.Minute)), Within: 10 * time.Second},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			spy, reported = spyHandler{}, time.Time{}
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-MTP-Deadline", test.Value)
+			if test.Token != "" {
+				req.Header.Set("Authorization", test.Token)
+			}
+			before := time.Now()
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			after := time.Now()
+			if !spy.OK {
+				t.Fatal("no deadline applied")
+			}
+			if !reported.Equal(spy.Deadline) {
+				t.Errorf("Deadline() = %v, want context deadline %v", reported, spy.Deadline)
+			}
+			if !test.Exact.IsZero() {
+				if !spy.Deadline.Equal(test.Exact) {
+					t.Errorf("spy.Deadline = %v, want %v", spy.Deadline, test.Exact)
+				}
+				return
+			}
+			if low, high := before.Add(test.Within), after.Add(test.Within); spy.Deadline.Before(low) || spy.Deadline.After(high) {
+				t.Errorf("spy.Deadline = %v, want within [%v, %v]", spy.Deadline, low, high)
+			}
+		})
+	}
+
+	t.Run("no-provisional", func(t *testing.T) {
+		spy = spyHandler{}
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-MTP-Deadline", asTimeFormat(now))
+		PromoteDeadline(&spy).ServeHTTP(httptest.NewRecorder(), req)
+		if spy.OK {
+			t.Errorf("spy.Deadline = %v, want none without EarlyDeadline", spy.Deadline)
+		}
+	})
+}
+
+func TestPromoteDeadlineSourceLayout(t *testing.T) {
+	clock := WithClock(func() time.Time { return now })
+	layout := WithSourceLayout(SourceHeader, time.RFC3339)
+	var spy spyHandler
+	h := EarlyDeadline("X-MTP-Deadline",
+		PromoteDeadline(&spy, clock, layout, WithMaxDeadline(time.Minute)),
+		clock, layout)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-MTP-Deadline", now.Add(time.Hour).Format(time.RFC3339))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got, want := rec.Code, http.StatusOK; got != want {
+		t.Fatalf("rec.Code = %v, want %v", got, want)
+	}
+	if got, want := spy.Deadline, now.Add(time.Minute); !spy.OK || !got.Equal(want) {
+		t.Errorf("spy.Deadline = %v, %v; want %v", got, spy.OK, want)
+	}
+}
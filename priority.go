@@ -0,0 +1,45 @@
+package httpdeadline
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultUrgency is the urgency that RFC 9218 assigns to requests that do not
+// signal one.
+const DefaultUrgency = 3
+
+// ParseUrgency extracts the urgency ("u") parameter from an RFC 9218 Priority
+// field value like "u=1, i".  Urgency ranges from 0 (most urgent) to 7 (least
+// urgent).  It reports false if the value carries no valid urgency, in which
+// case the returned urgency is [DefaultUrgency].
+func ParseUrgency(value string) (urgency int, ok bool) {
+	for _, member := range strings.Split(value, ",") {
+		member = strings.TrimSpace(member)
+		if params := strings.IndexByte(member, ';'); params >= 0 {
+			member = member[:params]
+		}
+		key, val, found := strings.Cut(member, "=")
+		if strings.TrimSpace(key) != "u" || !found {
+			continue
+		}
+		u, err := strconv.Atoi(strings.TrimSpace(val))
+		if err != nil || u < 0 || u > 7 {
+			// Per RFC 9218 Section 4, invalid values are ignored.
+			continue
+		}
+		urgency, ok = u, true
+	}
+	if !ok {
+		return DefaultUrgency, false
+	}
+	return urgency, true
+}
+
+// Urgency reports the urgency that the request signals in its Priority HTTP
+// header.  Requests without one have [DefaultUrgency].
+func Urgency(req *http.Request) int {
+	u, _ := ParseUrgency(strings.Join(req.Header.Values("Priority"), ","))
+	return u
+}
@@ -0,0 +1,71 @@
+package httpdeadline
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseUrgency(t *testing.T) {
+	for _, test := range []struct {
+		Value string
+
+		Urgency int
+		OK      bool
+	}{
+		{Value: "", Urgency: DefaultUrgency, OK: false},
+		{Value: "u=0", Urgency: 0, OK: true},
+		{Value: "u=7", Urgency: 7, OK: true},
+		{Value: "u=1, i", Urgency: 1, OK: true},
+		{Value: "i, u=5", Urgency: 5, OK: true},
+		{Value: "u=2;foo=bar", Urgency: 2, OK: true},
+		{Value: "u=1, u=6", Urgency: 6, OK: true},
+		{Value: "u=8", Urgency: DefaultUrgency, OK: false},
+		{Value: "u=-1", Urgency: DefaultUrgency, OK: false},
+		{Value: "u=high", Urgency: DefaultUrgency, OK: false},
+		{Value: "u", Urgency: DefaultUrgency, OK: false},
+		{Value: "i", Urgency: DefaultUrgency, OK: false},
+	} {
+		urgency, ok := ParseUrgency(test.Value)
+		if urgency != test.Urgency || ok != test.OK {
+			t.Errorf("ParseUrgency(%q) = %v, %v; want %v, %v", test.Value, urgency, ok, test.Urgency, test.OK)
+		}
+	}
+}
+
+func TestUrgencyCaps(t *testing.T) {
+	caps := [8]time.Duration{
+		60 * time.Second, 30 * time.Second, 20 * time.Second, 10 * time.Second,
+		5 * time.Second, 2 * time.Second, time.Second, 500 * time.Millisecond,
+	}
+	capOf := func(req *http.Request) time.Duration { return caps[Urgency(req)] }
+	far := time.Now().Add(time.Hour)
+	deadlineFor := func(priority string) time.Time {
+		t.Helper()
+		var spy spyHandler
+		srv := newServer(t, FromHeader("X-MTP-Deadline", &spy, WithMaxDeadlineFunc(capOf)))
+		req := newGetRequest(t, urlOf(t, srv))
+		req.Header.Set("X-MTP-Deadline", asTimeFormat(far))
+		if priority != "" {
+			req.Header.Set("Priority", priority)
+		}
+		resp, err := newClient().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if !spy.OK {
+			t.Fatalf("priority %q: no deadline applied", priority)
+		}
+		return spy.Deadline
+	}
+	high := deadlineFor("u=0")
+	normal := deadlineFor("")
+	low := deadlineFor("u=7, i")
+	if !high.After(normal) {
+		t.Errorf("deadline for u=0 (%v) not after default urgency (%v)", high, normal)
+	}
+	if !normal.After(low) {
+		t.Errorf("deadline for default urgency (%v) not after u=7 (%v)", normal, low)
+	}
+}
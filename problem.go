@@ -0,0 +1,67 @@
+package httpdeadline
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// problemTypeBase prefixes the RFC 9457 problem type URIs, which document each
+// rejection reason.
+const problemTypeBase = "https://pkg.go.dev/github.com/matttproud/httpdeadline#"
+
+// A problem is an RFC 9457 (formerly RFC 7807) problem details document.
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+	// RequestedDeadline is the deadline the client asked for, if it was
+	// understood.
+	RequestedDeadline *time.Time `json:"requested_deadline,omitempty"`
+	// GrantedDeadline is the deadline that policy clamped the requested one
+	// to, if it did.
+	GrantedDeadline *time.Time `json:"granted_deadline,omitempty"`
+}
+
+func writeProblem(w http.ResponseWriter, rec AuditRecord, status int) {
+	p := problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: rec.Err.Error(),
+	}
+	for _, r := range reasons {
+		if errors.Is(rec.Err, r.err) {
+			p.Type, p.Title = problemTypeBase+r.ident, r.title
+			break
+		}
+	}
+	if !rec.Requested.IsZero() {
+		p.RequestedDeadline = &rec.Requested
+	}
+	if !rec.granted.IsZero() {
+		p.GrantedDeadline = &rec.granted
+	}
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/problem+json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// WithProblemJSON makes rejections respond with an RFC 9457
+// application/problem+json document instead of plain text.  The document's
+// type links to the documentation of the rejection reason, and the extension
+// member "requested_deadline" carries the deadline the client requested when
+// it was understood, and "granted_deadline" the deadline that policy like
+// [WithMaxDeadline] clamped it to before rejecting it anyway (e.g., because the
+// clamped budget fell under [WithMinimumServiceTime]).
+//
+// Clamped deadlines that are served are not rejections, so the wrapped
+// handler's response is left alone; use [WithAuditSink] to observe them.
+func WithProblemJSON() Option {
+	return optionFunc(func(c *config) { c.problemJSON = true })
+}
@@ -0,0 +1,125 @@
+package httpdeadline
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithProblemJSON(t *testing.T) {
+	h := FromHeader("X-MTP-Deadline", new(spyHandler),
+		WithProblemJSON(),
+		WithNotBeforeHeader("X-MTP-Not-Before"),
+		WithMinimumServiceTime(func(*http.Request) time.Duration { return time.Minute }))
+	for _, test := range []struct {
+		Name   string
+		Header http.Header
+
+		Status    int
+		Type      string
+		Requested bool
+	}{
+		{
+			Name:   "empty",
+			Header: http.Header{"X-Mtp-Deadline": {""}},
+			Status: 400,
+			Type:   problemTypeBase + "ErrEmptyValue",
+		},
+		{
+			Name:   "parse",
+			Header: http.Header{"X-Mtp-Deadline": {"garbage"}},
+			Status: 400,
+			Type:   problemTypeBase + "ErrParse",
+		},
+		{
+			Name:      "budget-too-small",
+			Header:    http.Header{"X-Mtp-Deadline": {asTimeFormat(now)}},
+			Status:    400,
+			Type:      problemTypeBase + "ErrBudgetTooSmall",
+			Requested: true,
+		},
+		{
+			Name:   "too-early",
+			Header: http.Header{"X-Mtp-Not-Before": {asTimeFormat(time.Now().Add(time.Hour))}},
+			Status: 425,
+			Type:   problemTypeBase + "ErrTooEarly",
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header = test.Header
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, test.Status; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+			if got, want := rec.Header().Get("Content-Type"), "application/problem+json"; got != want {
+				t.Errorf("Content-Type = %q, want %q", got, want)
+			}
+			var doc map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("body %q is not JSON: %v", rec.Body, err)
+			}
+			if got, want := doc["type"], test.Type; got != want {
+				t.Errorf("type = %v, want %v", got, want)
+			}
+			if got, want := doc["status"], float64(test.Status); got != want {
+				t.Errorf("status = %v, want %v", got, want)
+			}
+			for _, member := range []string{"title", "detail"} {
+				if s, _ := doc[member].(string); s == "" {
+					t.Errorf("%v = %v, want non-empty string", member, doc[member])
+				}
+			}
+			requested, ok := doc["requested_deadline"].(string)
+			if ok != test.Requested {
+				t.Fatalf("requested_deadline = %v, want present %v", doc["requested_deadline"], test.Requested)
+			}
+			if ok {
+				if got, err := time.Parse(time.RFC3339, requested); err != nil || !got.Equal(now) {
+					t.Errorf("requested_deadline = %v, want %v", requested, now)
+				}
+			}
+			if granted, ok := doc["granted_deadline"]; ok {
+				t.Errorf("granted_deadline = %v, want absent", granted)
+			}
+		})
+	}
+}
+
+func TestWithProblemJSONClamped(t *testing.T) {
+	h := FromHeader("X-MTP-Deadline", new(spyHandler),
+		WithProblemJSON(),
+		WithClock(func() time.Time { return now }),
+		WithMaxDeadline(30*time.Second),
+		WithMinimumServiceTime(func(*http.Request) time.Duration { return time.Minute }))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-MTP-Deadline", asTimeFormat(now.Add(time.Hour)))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got, want := rec.Code, 400; got != want {
+		t.Errorf("rec.Code = %v, want %v", got, want)
+	}
+	if got, want := rec.Header().Get("Content-Type"), "application/problem+json"; got != want {
+		t.Errorf("Content-Type = %q, want %q", got, want)
+	}
+	var doc struct {
+		Type              string    `json:"type"`
+		RequestedDeadline time.Time `json:"requested_deadline"`
+		GrantedDeadline   time.Time `json:"granted_deadline"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("body %q is not JSON: %v", rec.Body, err)
+	}
+	if got, want := doc.Type, problemTypeBase+"ErrBudgetTooSmall"; got != want {
+		t.Errorf("type = %v, want %v", got, want)
+	}
+	if got, want := doc.RequestedDeadline, now.Add(time.Hour); !got.Equal(want) {
+		t.Errorf("requested_deadline = %v, want %v", got, want)
+	}
+	if got, want := doc.GrantedDeadline, now.Add(30*time.Second); !got.Equal(want) {
+		t.Errorf("granted_deadline = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+package httpdeadline
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"time"
+)
+
+// A ReverseProxyObserver records whether upstreams of an
+// [httputil.ReverseProxy] responded within the deadline of the proxied
+// request's context, typically one that [FromHeader] applied to the inbound
+// request.  Responses to requests without a deadline are not observed.
+//
+//	proxy := httputil.NewSingleHostReverseProxy(upstream)
+//	(&httpdeadline.ReverseProxyObserver{Header: "X-Deadline-Adherence"}).Wrap(proxy)
+//	mux.Handle("/", httpdeadline.FromHeader("X-MTP-Deadline", proxy))
+type ReverseProxyObserver struct {
+	// Header, if set, names a response header to annotate with the outcome,
+	// e.g., "met=?1, remaining_ms=120" or "met=?0, remaining_ms=-35", in
+	// the syntax of an RFC 8941 dictionary.
+	Header string
+	// Observe, if set, is called with each observed response, whether its
+	// deadline was met, and the budget remaining when the response headers
+	// arrived (negative if the deadline had passed).
+	Observe func(resp *http.Response, met bool, remaining time.Duration)
+}
+
+// ModifyResponse observes resp.  It is suitable as
+// [httputil.ReverseProxy.ModifyResponse] and never fails.
+func (o *ReverseProxyObserver) ModifyResponse(resp *http.Response) error {
+	if resp.Request == nil {
+		return nil
+	}
+	deadline, ok := resp.Request.Context().Deadline()
+	if !ok {
+		return nil
+	}
+	remaining := time.Until(deadline)
+	met := remaining > 0
+	if o.Header != "" {
+		bit := 0
+		if met {
+			bit = 1
+		}
+		resp.Header.Set(o.Header, fmt.Sprintf("met=?%d, remaining_ms=%d", bit, remaining.Milliseconds()))
+	}
+	if o.Observe != nil {
+		o.Observe(resp, met, remaining)
+	}
+	return nil
+}
+
+// Wrap installs o in p, after any ModifyResponse that p already has.
+func (o *ReverseProxyObserver) Wrap(p *httputil.ReverseProxy) {
+	prev := p.ModifyResponse
+	p.ModifyResponse = func(resp *http.Response) error {
+		if prev != nil {
+			if err := prev(resp); err != nil {
+				return err
+			}
+		}
+		return o.ModifyResponse(resp)
+	}
+}
@@ -0,0 +1,81 @@
+package httpdeadline
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestReverseProxyObserver(t *testing.T) {
+	upstream := newServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if d, err := time.ParseDuration(req.URL.Query().Get("delay")); err == nil {
+			time.Sleep(d)
+		}
+	}))
+	target, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		Name  string
+		Delay time.Duration
+
+		Met    bool
+		Header *regexp.Regexp
+	}{
+		{Name: "met", Header: regexp.MustCompile(`^met=\?1, remaining_ms=\d+$`), Met: true},
+		{Name: "missed", Delay: 150 * time.Millisecond, Header: regexp.MustCompile(`^met=\?0, remaining_ms=-\d+$`)},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var observed, met, prior bool
+			proxy := httputil.NewSingleHostReverseProxy(target)
+			proxy.ModifyResponse = func(*http.Response) error {
+				prior = true
+				return nil
+			}
+			o := &ReverseProxyObserver{
+				Header: "X-Deadline-Adherence",
+				Observe: func(_ *http.Response, m bool, _ time.Duration) {
+					observed, met = true, m
+				},
+			}
+			o.Wrap(proxy)
+			// Let slow upstreams respond after the deadline rather than have
+			// the transport abandon them, as an upstream that ignores the
+			// deadline would.
+			proxy.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				resp, err := http.DefaultTransport.RoundTrip(req.WithContext(context.WithoutCancel(req.Context())))
+				if resp != nil {
+					resp.Request = req
+				}
+				return resp, err
+			})
+			h := FromHeader("X-MTP-Deadline", proxy, WithLayout(time.RFC3339Nano))
+			req := httptest.NewRequest("GET", "/?delay="+test.Delay.String(), nil)
+			req.Header.Set("X-MTP-Deadline", time.Now().Add(100*time.Millisecond).Format(time.RFC3339Nano))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if !prior {
+				t.Error("prior ModifyResponse not called")
+			}
+			if !observed {
+				t.Fatal("response not observed")
+			}
+			if got, want := met, test.Met; got != want {
+				t.Errorf("met = %v, want %v", got, want)
+			}
+			if got := rec.Header().Get("X-Deadline-Adherence"); !test.Header.MatchString(got) {
+				t.Errorf("X-Deadline-Adherence = %q, want match for %v", got, test.Header)
+			}
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
@@ -0,0 +1,51 @@
+package httpdeadline
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]func(*http.Request) (string, bool))
+)
+
+// RegisterSource makes accessor available under name to [FromRegistered], so
+// that applications can centralize their custom deadline sources (e.g., a
+// cookie, a JWT claim, or a header that differs between deployments) behind
+// stable names, and change them without touching handler wiring.  accessor
+// reports the raw deadline value and whether the request carries one.
+//
+// Register sources from init functions or before creating the handlers that
+// use them.  RegisterSource is safe for concurrent use, but handlers resolve
+// names when they are created, so registering afterward does not affect them.
+// Like [database/sql.Register], RegisterSource panics if accessor is nil or
+// name is already registered.
+func RegisterSource(name string, accessor func(*http.Request) (string, bool)) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if accessor == nil {
+		panic("httpdeadline: RegisterSource accessor is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic(fmt.Sprintf("httpdeadline: RegisterSource called twice for source %q", name))
+	}
+	registry[name] = accessor
+}
+
+// FromRegistered is like [From] but finds the deadline with the accessor
+// registered under name with [RegisterSource].  It panics if name is not
+// registered.
+func FromRegistered(name string, h http.Handler, opts ...Option) http.Handler {
+	registryMu.RLock()
+	accessor, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		panic(fmt.Sprintf("httpdeadline: unknown source %q (forgotten RegisterSource?)", name))
+	}
+	return From(func(req *http.Request) (string, bool, error) {
+		val, ok := accessor(req)
+		return val, ok, nil
+	}, h, opts...)
+}
@@ -0,0 +1,77 @@
+package httpdeadline
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func init() {
+	RegisterSource("test-cookie", func(req *http.Request) (string, bool) {
+		cookie, err := req.Cookie("deadline")
+		if err != nil {
+			return "", false
+		}
+		return cookie.Value, true
+	})
+}
+
+func TestFromRegistered(t *testing.T) {
+	for _, test := range []struct {
+		Name   string
+		Cookie string
+
+		Status   int
+		Deadline time.Time
+		OK       bool
+	}{
+		{Name: "absent", Status: 200},
+		{Name: "applied", Cookie: now.Add(time.Minute).Format(time.RFC3339), Status: 200, Deadline: now.Add(time.Minute), OK: true},
+		{Name: "clamped", Cookie: now.Add(time.Hour).Format(time.RFC3339), Status: 200, Deadline: now.Add(2 * time.Minute), OK: true},
+		{Name: "malformed", Cookie: "garbage", Status: 400},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var spy spyHandler
+			h := FromRegistered("test-cookie", &spy,
+				WithClock(func() time.Time { return now }),
+				WithLayout(time.RFC3339),
+				WithMaxDeadline(2*time.Minute))
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.Cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "deadline", Value: test.Cookie})
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, test.Status; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+			if got, want := spy.Deadline, test.Deadline; !got.Equal(want) {
+				t.Errorf("spy.Deadline = %v, want %v", got, want)
+			}
+			if got, want := spy.OK, test.OK; got != want {
+				t.Errorf("spy.OK = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRegistryPanics(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		Do   func()
+	}{
+		{Name: "unknown", Do: func() { FromRegistered("no-such-source", new(spyHandler)) }},
+		{Name: "duplicate", Do: func() { RegisterSource("test-cookie", func(*http.Request) (string, bool) { return "", false }) }},
+		{Name: "nil", Do: func() { RegisterSource("test-nil", nil) }},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("did not panic")
+				}
+			}()
+			test.Do()
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package httpdeadline
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNonPositiveBudget indicates that a relative deadline value (see
+	// [WithDurationValues] and [WithMillisecondValues]) was zero or negative.
+	ErrNonPositiveBudget = errors.New("httpdeadline: non-positive budget")
+	// ErrBudgetOverflow indicates that a relative deadline value (see
+	// [WithDurationValues] and [WithMillisecondValues]) was too large to
+	// denote a meaningful deadline.
+	ErrBudgetOverflow = errors.New("httpdeadline: budget overflow")
+)
+
+// maxRelativeBudget bounds relative deadline values.  No request plausibly
+// needs a year; larger values are almost certainly unit confusion or abuse.
+const maxRelativeBudget = 365 * 24 * time.Hour
+
+// A relativeFormat parses deadline values that are budgets relative to when
+// the request is received rather than absolute times.
+type relativeFormat struct {
+	name  string // For metrics (see WithFormatMetrics).
+	parse func(string) (time.Duration, error)
+}
+
+// durationGrammar matches the unsigned grammar of [time.ParseDuration].
+var durationGrammar = regexp.MustCompile(`^(([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|μs|ms|s|m|h))+$`)
+
+// WithDurationValues interprets deadline values as budgets relative to when
+// the request is received, written in the grammar of [time.ParseDuration]
+// (e.g., "500ms", "2m30s", or "1.5h"), instead of as absolute times.  As the
+// package documentation explains, absolute times are preferable; use this only
+// for clients that cannot send them, and note that time spent in transit is
+// not deducted from the budget.
+// Zero and negative budgets are rejected with an error matching
+// [ErrNonPositiveBudget], and budgets over a year or beyond the range of
+// [time.Duration] with one matching [ErrBudgetOverflow].  It supersedes
+// [WithMillisecondValues] and vice versa.
+func WithDurationValues() Option {
+	return optionFunc(func(c *config) {
+		c.relative = &relativeFormat{name: "duration", parse: parseDurationBudget}
+	})
+}
+
+func parseDurationBudget(val string) (time.Duration, error) {
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		if durationGrammar.MatchString(strings.TrimLeft(val, "+-")) {
+			// Well-formed but out of range.
+			return 0, fmt.Errorf("%w: %q", ErrBudgetOverflow, val)
+		}
+		return 0, fmt.Errorf("%w: %v", ErrParse, err)
+	}
+	return checkBudget(val, d)
+}
+
+// checkBudget classifies budget d, parsed from val, as out of bounds.
+func checkBudget(val string, d time.Duration) (time.Duration, error) {
+	switch {
+	case d <= 0:
+		return 0, fmt.Errorf("%w: %q", ErrNonPositiveBudget, val)
+	case d > maxRelativeBudget:
+		return 0, fmt.Errorf("%w: %q exceeds %v", ErrBudgetOverflow, val, maxRelativeBudget)
+	}
+	return d, nil
+}
+
+// WithMillisecondValues is like [WithDurationValues] except that values are
+// whole numbers of milliseconds, as in the common "X-Timeout-Ms: 500"
+// convention:
+//
+//	mux.Handle("/teapotz", httpdeadline.FromHeader("X-Timeout-Ms", teapotz,
+//		httpdeadline.WithMillisecondValues()))
+//
+// Parsing is strict: surrounding whitespace is trimmed, but fractions, plus
+// signs, and leading zeros are rejected with an error matching [ErrParse].
+// Zero and negative values are rejected with one matching
+// [ErrNonPositiveBudget], and values over a year with one matching
+// [ErrBudgetOverflow].  It supersedes [WithDurationValues] and vice versa.
+func WithMillisecondValues() Option {
+	return optionFunc(func(c *config) {
+		c.relative = &relativeFormat{name: "milliseconds", parse: parseMillisecondBudget}
+	})
+}
+
+func parseMillisecondBudget(val string) (time.Duration, error) {
+	digits, negative := strings.CutPrefix(strings.TrimSpace(val), "-")
+	if digits == "" || strings.Trim(digits, "0123456789") != "" || (len(digits) > 1 && digits[0] == '0') {
+		return 0, fmt.Errorf("%w: %q is not a whole number of milliseconds", ErrParse, val)
+	}
+	if negative {
+		return 0, fmt.Errorf("%w: %q", ErrNonPositiveBudget, val)
+	}
+	ms, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || ms > int64(maxRelativeBudget/time.Millisecond) {
+		return 0, fmt.Errorf("%w: %q exceeds %v", ErrBudgetOverflow, val, maxRelativeBudget)
+	}
+	return checkBudget(val, time.Duration(ms)*time.Millisecond)
+}
+
+// WithReceivedTimeKey anchors relative budgets (see [WithDurationValues],
+// [WithMillisecondValues], and [WithCrossCheckTolerance]) to the
+// [time.Time] stored under key in the request's context rather than to when
+// the middleware runs, so that time spent in earlier middleware is deducted
+// from the budget.  Stamp the time per request in the outermost handler:
+//
+//	srv := &http.Server{
+//		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+//			ctx := context.WithValue(req.Context(), receivedKey{}, time.Now())
+//			mux.ServeHTTP(w, req.WithContext(ctx))
+//		}),
+//	}
+//
+// Do not stamp it in [http.Server.ConnContext] or [http.Server.BaseContext]:
+// they run once per connection or listener, so later requests on the same
+// connection (e.g., with keep-alive or HTTP/2) would be anchored to when it
+// was accepted, shrinking their budgets or expiring them outright.  Requests
+// without a time under key, or with one after the middleware runs, are
+// anchored as usual.  Caps like [WithMaxDeadline] still apply from when the
+// middleware runs.
+func WithReceivedTimeKey(key any) Option {
+	if key == nil {
+		return invalidf("nil received time key")
+	}
+	return optionFunc(func(c *config) { c.receivedTimeKey = key })
+}
+
+// anchor returns the time relative budgets in req count from, given that the
+// middleware runs at now.
+func (c *config) anchor(req *http.Request, now time.Time) time.Time {
+	if c.receivedTimeKey == nil {
+		return now
+	}
+	if received, ok := req.Context().Value(c.receivedTimeKey).(time.Time); ok && !received.IsZero() && received.Before(now) {
+		return received
+	}
+	return now
+}
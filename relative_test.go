@@ -0,0 +1,251 @@
+package httpdeadline
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseDurationBudget(t *testing.T) {
+	for _, test := range []struct {
+		Value string
+
+		Budget time.Duration
+		Err    error
+	}{
+		{Value: "500ms", Budget: 500 * time.Millisecond},
+		{Value: "2m30s", Budget: 2*time.Minute + 30*time.Second},
+		{Value: "1.5h", Budget: 90 * time.Minute},
+		{Value: "1h2m3s4ms5us6ns", Budget: time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond + 5*time.Microsecond + 6},
+		{Value: ".5s", Budget: 500 * time.Millisecond},
+		{Value: "+5s", Budget: 5 * time.Second},
+		{Value: "3µs", Budget: 3 * time.Microsecond},
+		{Value: "", Err: ErrParse},
+		{Value: "5", Err: ErrParse},
+		{Value: "five seconds", Err: ErrParse},
+		{Value: "5s ", Err: ErrParse},
+		{Value: "0", Err: ErrNonPositiveBudget},
+		{Value: "0s", Err: ErrNonPositiveBudget},
+		{Value: "-5s", Err: ErrNonPositiveBudget},
+		{Value: "1000000h", Err: ErrBudgetOverflow},
+		{Value: "8761h", Err: ErrBudgetOverflow},
+		{Value: "9999999999h", Err: ErrBudgetOverflow},
+		{Value: "-9999999999h", Err: ErrBudgetOverflow},
+	} {
+		budget, err := parseDurationBudget(test.Value)
+		if budget != test.Budget || !errors.Is(err, test.Err) || (test.Err == nil) != (err == nil) {
+			t.Errorf("parseDurationBudget(%q) = %v, %v; want %v, %v", test.Value, budget, err, test.Budget, test.Err)
+		}
+	}
+}
+
+func TestWithDurationValues(t *testing.T) {
+	clock := func() time.Time { return now }
+	for _, test := range []struct {
+		Value string
+
+		Code     int
+		Deadline time.Time
+	}{
+		{Value: "2m30s", Code: 200, Deadline: now.Add(2*time.Minute + 30*time.Second)},
+		{Value: "1.5h", Code: 200, Deadline: now.Add(5 * time.Minute)},
+		{Value: "-5s", Code: 400},
+		{Value: "1000000h", Code: 400},
+		{Value: asTimeFormat(now), Code: 400},
+	} {
+		var spy spyHandler
+		h := FromHeader("X-MTP-Timeout", &spy, WithDurationValues(), WithClock(clock), WithMaxDeadline(5*time.Minute))
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-MTP-Timeout", test.Value)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if got, want := rec.Code, test.Code; got != want {
+			t.Errorf("%q: rec.Code = %v, want %v", test.Value, got, want)
+		}
+		if test.Deadline.IsZero() {
+			continue
+		}
+		if got, want := spy.Deadline, test.Deadline; !spy.OK || !got.Equal(want) {
+			t.Errorf("%q: spy.Deadline = %v (%v), want %v", test.Value, got, spy.OK, want)
+		}
+	}
+}
+
+func TestParseMillisecondBudget(t *testing.T) {
+	for _, test := range []struct {
+		Value string
+
+		Budget time.Duration
+		Err    error
+	}{
+		{Value: "500", Budget: 500 * time.Millisecond},
+		{Value: " 500 ", Budget: 500 * time.Millisecond},
+		{Value: "1", Budget: time.Millisecond},
+		{Value: "31536000000", Budget: maxRelativeBudget},
+		{Value: "0", Err: ErrNonPositiveBudget},
+		{Value: "-1", Err: ErrNonPositiveBudget},
+		{Value: "-0", Err: ErrNonPositiveBudget},
+		{Value: "", Err: ErrParse},
+		{Value: "-", Err: ErrParse},
+		{Value: "+500", Err: ErrParse},
+		{Value: "500.5", Err: ErrParse},
+		{Value: "500ms", Err: ErrParse},
+		{Value: "0500", Err: ErrParse},
+		{Value: "5 00", Err: ErrParse},
+		{Value: "1e3", Err: ErrParse},
+		{Value: "31536000001", Err: ErrBudgetOverflow},
+		{Value: "99999999999999999999999", Err: ErrBudgetOverflow},
+	} {
+		budget, err := parseMillisecondBudget(test.Value)
+		if budget != test.Budget || !errors.Is(err, test.Err) || (test.Err == nil) != (err == nil) {
+			t.Errorf("parseMillisecondBudget(%q) = %v, %v; want %v, %v", test.Value, budget, err, test.Budget, test.Err)
+		}
+	}
+}
+
+func TestWithMillisecondValues(t *testing.T) {
+	var spy spyHandler
+	h := FromHeader("X-Timeout-Ms", &spy, WithMillisecondValues(), WithClock(func() time.Time { return now }))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Timeout-Ms", "500")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got, want := rec.Code, 200; got != want {
+		t.Errorf("rec.Code = %v, want %v", got, want)
+	}
+	if got, want := spy.Deadline, now.Add(500*time.Millisecond); !spy.OK || !got.Equal(want) {
+		t.Errorf("spy.Deadline = %v (%v), want %v", got, spy.OK, want)
+	}
+}
+
+func TestFromStartAndOffset(t *testing.T) {
+	clock := func() time.Time { return now }
+	for _, test := range []struct {
+		Name   string
+		Start  *string
+		Offset *string
+
+		Code     int
+		Err      error
+		Deadline time.Time
+	}{
+		{Name: "neither", Code: 200},
+		{Name: "both", Start: ptr(asTimeFormat(now.Add(-time.Second))), Offset: ptr("31s"), Code: 200, Deadline: now.Add(30 * time.Second)},
+		{Name: "clamped", Start: ptr(asTimeFormat(now)), Offset: ptr("1h"), Code: 200, Deadline: now.Add(time.Minute)},
+		{Name: "start-only", Start: ptr(asTimeFormat(now)), Code: 400, Err: ErrParse},
+		{Name: "offset-only", Offset: ptr("30s"), Code: 400, Err: ErrParse},
+		{Name: "past", Start: ptr(asTimeFormat(now.Add(-time.Minute))), Offset: ptr("30s"), Code: 400, Err: ErrDeadlineExpired},
+		{Name: "bad-start", Start: ptr("garbage"), Offset: ptr("30s"), Code: 400, Err: ErrParse},
+		{Name: "negative-offset", Start: ptr(asTimeFormat(now)), Offset: ptr("-30s"), Code: 400, Err: ErrNonPositiveBudget},
+		{Name: "empty-offset", Start: ptr(asTimeFormat(now)), Offset: ptr(""), Code: 400, Err: ErrEmptyValue},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var (
+				spy spyHandler
+				got AuditRecord
+			)
+			h := FromStartAndOffset("X-Edge-Start", "X-Edge-Budget", &spy,
+				WithClock(clock),
+				WithMaxDeadline(time.Minute),
+				WithAuditSink(func(rec AuditRecord) { got = rec }))
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.Start != nil {
+				req.Header.Set("X-Edge-Start", *test.Start)
+			}
+			if test.Offset != nil {
+				req.Header.Set("X-Edge-Budget", *test.Offset)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, test.Code; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+			if !errors.Is(got.Err, test.Err) || (test.Err == nil) != (got.Err == nil) {
+				t.Errorf("rec.Err = %v, want %v", got.Err, test.Err)
+			}
+			if got, want := spy.OK, !test.Deadline.IsZero(); got != want {
+				t.Fatalf("spy.OK = %v, want %v", got, want)
+			}
+			if got, want := spy.Deadline, test.Deadline; spy.OK && !got.Equal(want) {
+				t.Errorf("spy.Deadline = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestWithCrossCheckTolerance(t *testing.T) {
+	clock := func() time.Time { return now }
+	for _, test := range []struct {
+		Name  string
+		Start time.Time
+
+		Deadline time.Time
+		Outcome  Outcome
+	}{
+		{Name: "agree", Start: now, Deadline: now.Add(30 * time.Second), Outcome: OutcomeApplied},
+		{Name: "within-tolerance", Start: now.Add(4 * time.Second), Deadline: now.Add(34 * time.Second), Outcome: OutcomeApplied},
+		{Name: "slow-clock", Start: now.Add(-10 * time.Second), Deadline: now.Add(20 * time.Second), Outcome: OutcomeApplied},
+		{Name: "fast-clock", Start: now.Add(10 * time.Second), Deadline: now.Add(30 * time.Second), Outcome: OutcomeClamped},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var (
+				spy spyHandler
+				got AuditRecord
+			)
+			h := FromStartAndOffset("X-Edge-Start", "X-Edge-Budget", &spy,
+				WithClock(clock),
+				WithCrossCheckTolerance(5*time.Second),
+				WithAuditSink(func(rec AuditRecord) { got = rec }))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-Edge-Start", asTimeFormat(test.Start))
+			req.Header.Set("X-Edge-Budget", "30s")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got, want := spy.Deadline, test.Deadline; !got.Equal(want) {
+				t.Errorf("spy.Deadline = %v, want %v", got, want)
+			}
+			if got, want := got.Outcome, test.Outcome; got != want {
+				t.Errorf("rec.Outcome = %v, want %v", got, want)
+			}
+		})
+	}
+	if _, err := NewPolicy(WithCrossCheckTolerance(0)); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("NewPolicy(WithCrossCheckTolerance(0)) = %v, want %v", err, ErrInvalidOption)
+	}
+}
+
+func TestWithReceivedTimeKey(t *testing.T) {
+	type receivedKey struct{}
+	for _, test := range []struct {
+		Name     string
+		Received any
+
+		Deadline time.Time
+	}{
+		{Name: "earlier", Received: now.Add(-2 * time.Second), Deadline: now.Add(3 * time.Second)},
+		{Name: "absent", Deadline: now.Add(5 * time.Second)},
+		{Name: "later", Received: now.Add(2 * time.Second), Deadline: now.Add(5 * time.Second)},
+		{Name: "wrong-type", Received: "garbage", Deadline: now.Add(5 * time.Second)},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var spy spyHandler
+			h := FromHeader("X-MTP-Budget", &spy,
+				WithDurationValues(),
+				WithClock(func() time.Time { return now }),
+				WithReceivedTimeKey(receivedKey{}))
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.Received != nil {
+				req = req.WithContext(context.WithValue(req.Context(), receivedKey{}, test.Received))
+			}
+			req.Header.Set("X-MTP-Budget", "5s")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got, want := spy.Deadline, test.Deadline; !spy.OK || !got.Equal(want) {
+				t.Errorf("spy.Deadline = %v (%v), want %v", got, spy.OK, want)
+			}
+		})
+	}
+	if _, err := NewPolicy(WithReceivedTimeKey(nil)); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("NewPolicy(WithReceivedTimeKey(nil)) = %v, want %v", err, ErrInvalidOption)
+	}
+}
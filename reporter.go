@@ -0,0 +1,49 @@
+package httpdeadline
+
+import (
+	"net/http"
+	"time"
+)
+
+// A DeadlineReporter is an [http.ResponseWriter] that reports the deadline
+// applied to the request it responds to.  See [WithResponseWriterReporting].
+type DeadlineReporter interface {
+	http.ResponseWriter
+	// AppliedDeadline reports the deadline applied to the request and whether
+	// one was.
+	AppliedDeadline() (time.Time, bool)
+}
+
+// WithResponseWriterReporting passes the wrapped handler an
+// [http.ResponseWriter] that implements [DeadlineReporter], so that handlers
+// that hold only the writer (e.g., access-log middleware that logs once the
+// response is done) can learn the deadline:
+//
+//	if r, ok := w.(httpdeadline.DeadlineReporter); ok {
+//		deadline, ok := r.AppliedDeadline()
+//		// ...
+//	}
+//
+// Such middleware must be wrapped by this package's, not wrap it.  Prefer
+// [Deadline] where the request's context is available.
+func WithResponseWriterReporting() Option {
+	return optionFunc(func(c *config) { c.reportWriter = true })
+}
+
+// A reportingWriter implements DeadlineReporter.
+type reportingWriter struct {
+	http.ResponseWriter
+	deadline time.Time
+	ok       bool
+}
+
+func (w *reportingWriter) AppliedDeadline() (time.Time, bool) { return w.deadline, w.ok }
+
+func (w *reportingWriter) FlushError() error {
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *reportingWriter) Flush() { w.FlushError() }
+
+// Unwrap lets [http.ResponseController] reach the underlying writer.
+func (w *reportingWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,49 @@
+package httpdeadline
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithResponseWriterReporting(t *testing.T) {
+	for _, test := range []struct {
+		Name  string
+		Value *string
+
+		Deadline time.Time
+		OK       bool
+	}{
+		{Name: "applied", Value: ptr(asTimeFormat(now.Add(time.Minute))), Deadline: now.Add(time.Minute), OK: true},
+		{Name: "absent"},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var (
+				deadline time.Time
+				ok       bool
+				reporter bool
+			)
+			// logger stands in for access-log middleware that holds only the
+			// writer.
+			logger := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				var r DeadlineReporter
+				if r, reporter = w.(DeadlineReporter); reporter {
+					deadline, ok = r.AppliedDeadline()
+				}
+			})
+			h := FromHeader("X-MTP-Deadline", logger, WithClock(func() time.Time { return now }), WithResponseWriterReporting())
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.Value != nil {
+				req.Header.Set("X-MTP-Deadline", *test.Value)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if !reporter {
+				t.Fatal("writer does not implement DeadlineReporter")
+			}
+			if got, want := deadline, test.Deadline; !got.Equal(want) || ok != test.OK {
+				t.Errorf("AppliedDeadline() = %v, %v; want %v, %v", got, ok, want, test.OK)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package httpdeadline
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WithScheduler hands the wrapped handler to schedule rather than running it
+// directly, so that a custom scheduler can order or gate request work by
+// deadline (e.g., running the tightest budgets first).  schedule receives the
+// request's context and deadline, and must call run exactly once, on any
+// goroutine, or drop it once ctx is done.  The middleware waits for run to
+// return.  If the request's context ends before the scheduler starts run, the
+// middleware answers with 504 Gateway Timeout instead, and run does nothing.
+// The handler's panics are not recovered on goroutines other than the
+// request's.  Requests without deadlines run directly.
+//
+// ctx ends at the deadline even in [WithAdvisoryOnly] and [WithStreamingMode],
+// where the request's context does not, so that requests whose run the
+// scheduler dropped are still answered.
+func WithScheduler(schedule func(ctx context.Context, deadline time.Time, run func())) Option {
+	if schedule == nil {
+		return invalidf("nil scheduler")
+	}
+	return optionFunc(func(c *config) { c.scheduler = schedule })
+}
+
+// schedule serves req through the configured scheduler.
+func (h *handler) schedule(w http.ResponseWriter, req *http.Request, deadline time.Time) {
+	var (
+		mu        sync.Mutex
+		started   bool
+		abandoned bool
+		done      = make(chan struct{})
+	)
+	run := func() {
+		mu.Lock()
+		if abandoned || started {
+			mu.Unlock()
+			return
+		}
+		started = true
+		mu.Unlock()
+		defer close(done)
+		h.next.ServeHTTP(w, req)
+	}
+	ctx := req.Context()
+	if h.cfg.advisory || h.cfg.streaming {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadlineCause(ctx, deadline, ErrDeadlineExceeded)
+		defer cancel()
+	}
+	h.cfg.scheduler(ctx, deadline, run)
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+	mu.Lock()
+	if started {
+		mu.Unlock()
+		<-done
+		return
+	}
+	abandoned = true
+	mu.Unlock()
+	http.Error(w, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
+}
@@ -0,0 +1,149 @@
+package httpdeadline
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// deadlineScheduler queues tasks until drained, then runs them in deadline
+// order.
+type deadlineScheduler struct {
+	mu    sync.Mutex
+	tasks []scheduledTask
+}
+
+type scheduledTask struct {
+	deadline time.Time
+	run      func()
+}
+
+func (s *deadlineScheduler) schedule(_ context.Context, deadline time.Time, run func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks = append(s.tasks, scheduledTask{deadline, run})
+}
+
+func (s *deadlineScheduler) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.tasks)
+}
+
+func (s *deadlineScheduler) drain() {
+	s.mu.Lock()
+	tasks := s.tasks
+	s.tasks = nil
+	s.mu.Unlock()
+	slices.SortFunc(tasks, func(a, b scheduledTask) int { return a.deadline.Compare(b.deadline) })
+	for _, task := range tasks {
+		task.run()
+	}
+}
+
+func TestWithScheduler(t *testing.T) {
+	var (
+		sched deadlineScheduler
+		mu    sync.Mutex
+		order []time.Time
+	)
+	h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		deadline, _ := Deadline(req.Context())
+		mu.Lock()
+		order = append(order, deadline)
+		mu.Unlock()
+	}), WithLayout(time.RFC3339Nano), WithScheduler(sched.schedule))
+	base := time.Now().Add(time.Hour)
+	deadlines := []time.Time{base.Add(3 * time.Second), base.Add(time.Second), base.Add(2 * time.Second)}
+	var wg sync.WaitGroup
+	for _, deadline := range deadlines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-MTP-Deadline", deadline.Format(time.RFC3339Nano))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, http.StatusOK; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+		}()
+	}
+	for sched.len() < len(deadlines) {
+		time.Sleep(time.Millisecond)
+	}
+	sched.drain()
+	wg.Wait()
+	want := slices.Clone(deadlines)
+	slices.SortFunc(want, time.Time.Compare)
+	if !slices.EqualFunc(order, want, time.Time.Equal) {
+		t.Errorf("handlers ran in order %v, want %v", order, want)
+	}
+}
+
+func TestWithSchedulerAbandoned(t *testing.T) {
+	var (
+		ran  bool
+		task func()
+	)
+	h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(http.ResponseWriter, *http.Request) { ran = true }),
+		WithLayout(time.RFC3339Nano),
+		WithScheduler(func(_ context.Context, _ time.Time, run func()) { task = run }))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-MTP-Deadline", time.Now().Add(50*time.Millisecond).Format(time.RFC3339Nano))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got, want := rec.Code, http.StatusGatewayTimeout; got != want {
+		t.Errorf("rec.Code = %v, want %v", got, want)
+	}
+	task() // Too late.
+	if ran {
+		t.Error("handler ran after the middleware abandoned it")
+	}
+}
+
+func TestWithSchedulerAbsent(t *testing.T) {
+	var spy spyHandler
+	h := FromHeader("X-MTP-Deadline", &spy, WithScheduler(func(context.Context, time.Time, func()) {
+		t.Error("scheduler called for request without deadline")
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestWithSchedulerUncancelled(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		Opt  Option
+	}{
+		{Name: "advisory", Opt: WithAdvisoryOnly()},
+		{Name: "streaming", Opt: WithStreamingMode(func(*http.Request) {})},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			// The scheduler drops run once its context is done.
+			h := FromHeader("X-MTP-Deadline", new(spyHandler),
+				WithLayout(time.RFC3339Nano),
+				test.Opt,
+				WithScheduler(func(context.Context, time.Time, func()) {}))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-MTP-Deadline", time.Now().Add(50*time.Millisecond).Format(time.RFC3339Nano))
+			rec := httptest.NewRecorder()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				h.ServeHTTP(rec, req)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("request hung after the scheduler dropped it")
+			}
+			if got, want := rec.Code, http.StatusGatewayTimeout; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+		})
+	}
+}
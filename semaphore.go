@@ -0,0 +1,55 @@
+package httpdeadline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrWouldMissDeadline indicates that a resource could not be acquired within
+// the request's deadline.
+var ErrWouldMissDeadline = errors.New("httpdeadline: acquisition would miss deadline")
+
+// AcquireWithin acquires a slot of the counting semaphore sem, which is a
+// buffered channel whose capacity is the number of slots.  Release the slot by
+// receiving from sem.
+//
+// Acquisition is bounded by ctx's deadline, like the one that [FromHeader]
+// applies.  If no slot is free immediately and less than typical (the time an
+// acquisition usually takes under contention) remains before the deadline,
+// AcquireWithin fails fast without waiting.  In both cases the error matches
+// [ErrWouldMissDeadline].  If ctx is otherwise cancelled, ctx.Err() is
+// returned.
+//
+//	sem := make(chan struct{}, 10)
+//
+//	func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+//		if err := httpdeadline.AcquireWithin(r.Context(), sem, 50*time.Millisecond); err != nil {
+//			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+//			return
+//		}
+//		defer func() { <-sem }()
+//		// ...
+//	}
+func AcquireWithin(ctx context.Context, sem chan<- struct{}, typical time.Duration) error {
+	select {
+	case sem <- struct{}{}:
+		return nil
+	default:
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining < typical {
+			return fmt.Errorf("%w: %v remaining, acquisition typically takes %v", ErrWouldMissDeadline, remaining, typical)
+		}
+	}
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		if err := ctx.Err(); !errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		return fmt.Errorf("%w: %w", ErrWouldMissDeadline, ctx.Err())
+	}
+}
@@ -0,0 +1,64 @@
+package httpdeadline
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAcquireWithin(t *testing.T) {
+	t.Run("free", func(t *testing.T) {
+		sem := make(chan struct{}, 1)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		if err := AcquireWithin(ctx, sem, time.Hour); err != nil {
+			t.Errorf("AcquireWithin() = %v, want nil", err)
+		}
+	})
+	t.Run("released-within-budget", func(t *testing.T) {
+		sem := make(chan struct{}, 1)
+		sem <- struct{}{}
+		time.AfterFunc(10*time.Millisecond, func() { <-sem })
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := AcquireWithin(ctx, sem, time.Millisecond); err != nil {
+			t.Errorf("AcquireWithin() = %v, want nil", err)
+		}
+	})
+	t.Run("budget-below-typical", func(t *testing.T) {
+		sem := make(chan struct{}, 1)
+		sem <- struct{}{}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		start := time.Now()
+		if err := AcquireWithin(ctx, sem, time.Minute); !errors.Is(err, ErrWouldMissDeadline) {
+			t.Errorf("AcquireWithin() = %v, want %v", err, ErrWouldMissDeadline)
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("AcquireWithin() took %v, want fast failure", elapsed)
+		}
+	})
+	t.Run("deadline-fires", func(t *testing.T) {
+		sem := make(chan struct{}, 1)
+		sem <- struct{}{}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := AcquireWithin(ctx, sem, 0)
+		if !errors.Is(err, ErrWouldMissDeadline) {
+			t.Errorf("AcquireWithin() = %v, want %v", err, ErrWouldMissDeadline)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("AcquireWithin() = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		sem := make(chan struct{}, 1)
+		sem <- struct{}{}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := AcquireWithin(ctx, sem, 0); !errors.Is(err, context.Canceled) || errors.Is(err, ErrWouldMissDeadline) {
+			t.Errorf("AcquireWithin() = %v, want %v", err, context.Canceled)
+		}
+	})
+}
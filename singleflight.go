@@ -0,0 +1,80 @@
+package httpdeadline
+
+import (
+	"context"
+	"sync"
+)
+
+// A SingleFlight deduplicates concurrent calls for the same key, such as
+// requests from many clients for the same expensive computation, into one
+// shared call whose budget is the latest of the waiting callers' deadlines.
+// Each caller is released as soon as either the shared call completes or its
+// own context (e.g., one bounded by [FromHeader]) is done, whichever comes
+// first.  The zero value is ready for use.
+type SingleFlight[V any] struct {
+	mu     sync.Mutex
+	flight map[string]*flight[V]
+}
+
+type flight[V any] struct {
+	done    chan struct{}
+	val     V
+	err     error
+	waiters int // Guarded by SingleFlight.mu.
+	cancel  context.CancelCauseFunc
+}
+
+// Do calls fn for key unless a call for key is already in flight, in which
+// case it waits for that call's result instead.  If ctx is done first, Do
+// returns [context.Cause] of ctx without waiting further.
+//
+// fn receives a context that carries the values of the first caller's ctx but
+// not its deadline.  Instead, it is cancelled once every caller waiting on the
+// call has given up, with the [context.Cause] of the last to do so (e.g.,
+// [ErrDeadlineExceeded]), so the shared work runs until the latest of their
+// deadlines and no longer.  Once fn returns, later calls for key start anew.
+func (g *SingleFlight[V]) Do(ctx context.Context, key string, fn func(context.Context) (V, error)) (V, error) {
+	g.mu.Lock()
+	f, ok := g.flight[key]
+	if !ok {
+		if g.flight == nil {
+			g.flight = make(map[string]*flight[V])
+		}
+		work, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+		f = &flight[V]{done: make(chan struct{}), cancel: cancel}
+		g.flight[key] = f
+		go g.run(work, key, f, fn)
+	}
+	f.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if f.waiters--; f.waiters == 0 {
+		f.cancel(context.Cause(ctx))
+		g.forget(key, f)
+	}
+	var zero V
+	return zero, context.Cause(ctx)
+}
+
+func (g *SingleFlight[V]) run(ctx context.Context, key string, f *flight[V], fn func(context.Context) (V, error)) {
+	defer f.cancel(nil)
+	f.val, f.err = fn(ctx)
+	g.mu.Lock()
+	g.forget(key, f)
+	g.mu.Unlock()
+	close(f.done)
+}
+
+// forget stops new callers from joining f.  g.mu must be held.
+func (g *SingleFlight[V]) forget(key string, f *flight[V]) {
+	if g.flight[key] == f {
+		delete(g.flight, key)
+	}
+}
@@ -0,0 +1,109 @@
+package httpdeadline
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSingleFlight(t *testing.T) {
+	var (
+		g       SingleFlight[string]
+		calls   atomic.Int32
+		release = make(chan struct{})
+		started = make(chan struct{})
+	)
+	fn := func(ctx context.Context) (string, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		select {
+		case <-release:
+			return "teapot", nil
+		case <-ctx.Done():
+			return "", context.Cause(ctx)
+		}
+	}
+	short, cancelShort := context.WithDeadlineCause(context.Background(), time.Now().Add(50*time.Millisecond), ErrDeadlineExceeded)
+	defer cancelShort()
+	long, cancelLong := context.WithDeadlineCause(context.Background(), time.Now().Add(time.Minute), ErrDeadlineExceeded)
+	defer cancelLong()
+
+	type result struct {
+		val string
+		err error
+	}
+	var (
+		wg                sync.WaitGroup
+		shortRes, longRes result
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		shortRes.val, shortRes.err = g.Do(short, "k", fn)
+	}()
+	<-started
+	go func() {
+		defer wg.Done()
+		longRes.val, longRes.err = g.Do(long, "k", fn)
+	}()
+	for joined := false; !joined; time.Sleep(time.Millisecond) {
+		g.mu.Lock()
+		joined = g.flight["k"].waiters == 2
+		g.mu.Unlock()
+	}
+	<-short.Done()
+	time.Sleep(10 * time.Millisecond) // Let the short waiter give up.
+	close(release)
+	wg.Wait()
+
+	if got, want := calls.Load(), int32(1); got != want {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+	if !errors.Is(shortRes.err, ErrDeadlineExceeded) {
+		t.Errorf("short waiter: err = %v, want %v", shortRes.err, ErrDeadlineExceeded)
+	}
+	if longRes.val != "teapot" || longRes.err != nil {
+		t.Errorf("long waiter: %q, %v; want %q, nil", longRes.val, longRes.err, "teapot")
+	}
+}
+
+func TestSingleFlightAbandoned(t *testing.T) {
+	var (
+		g      SingleFlight[int]
+		workCh = make(chan error, 1)
+	)
+	fn := func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		workCh <- context.Cause(ctx)
+		return 0, context.Cause(ctx)
+	}
+	var wg sync.WaitGroup
+	for _, d := range []time.Duration{20 * time.Millisecond, 60 * time.Millisecond} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithDeadlineCause(context.Background(), time.Now().Add(d), ErrDeadlineExceeded)
+			defer cancel()
+			if _, err := g.Do(ctx, "k", fn); !errors.Is(err, ErrDeadlineExceeded) {
+				t.Errorf("waiter with %v budget: err = %v, want %v", d, err, ErrDeadlineExceeded)
+			}
+		}()
+	}
+	start := time.Now()
+	select {
+	case err := <-workCh:
+		if !errors.Is(err, ErrDeadlineExceeded) {
+			t.Errorf("work cause = %v, want %v", err, ErrDeadlineExceeded)
+		}
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Errorf("work cancelled after %v, want after latest deadline", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("work not cancelled after all waiters gave up")
+	}
+	wg.Wait()
+}
@@ -0,0 +1,111 @@
+package httpdeadline
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// Attribute keys for structured logging.  They are stable, so log pipelines
+// may index them.
+const (
+	// AttrApplied is the key of the applied deadline, a [time.Time].
+	AttrApplied = "httpdeadline.applied"
+	// AttrOutcome is the key of how the deadline was determined, a string
+	// holding an [Outcome] (e.g., "applied", "clamped", or "default").
+	AttrOutcome = "httpdeadline.outcome"
+	// AttrSource is the key of where the client's deadline value was found,
+	// a string holding a [Source] (e.g., "header" or "query").
+	AttrSource = "httpdeadline.source"
+	// AttrRemainingMS is the key of the budget remaining before the applied
+	// deadline, an int64 count of milliseconds.  It is negative once the
+	// deadline has passed.
+	AttrRemainingMS = "httpdeadline.remaining_ms"
+)
+
+// WithLogger logs every deadline decision to logger: rejections at
+// [slog.LevelInfo] and everything else at [slog.LevelDebug].  Records carry
+// [AttrOutcome], [AttrSource] when the client sent a value, and, when a
+// deadline is applied, [AttrApplied] and [AttrRemainingMS] (measured from
+// the decision).  Rejections additionally
+// carry the reason under the key "error".  See [WithLogSampling] to log only
+// some decisions.
+func WithLogger(logger *slog.Logger) Option {
+	if logger == nil {
+		return invalidf("nil logger")
+	}
+	return optionFunc(func(c *config) { c.logger = logger })
+}
+
+// WithLogSampling makes [WithLogger] log only about the fraction rate of
+// decisions other than rejections, which high request rates would otherwise
+// flood logs with.  Rejections, which are rarer and more telling, are always
+// logged.  Sampling is deterministic and evenly spread: with a rate of 0.1,
+// every tenth such decision is logged.  Handlers created with the same Option
+// (or a [Policy] holding it) share the count.  rate must be in (0, 1].
+func WithLogSampling(rate float64) Option {
+	if !(rate > 0 && rate <= 1) {
+		return invalidf("log sampling rate %v outside (0, 1]", rate)
+	}
+	s := &logSampler{rate: rate}
+	return optionFunc(func(c *config) { c.logSampler = s })
+}
+
+// A logSampler selects every (1/rate)th decision for logging.
+type logSampler struct {
+	rate float64
+	n    atomic.Uint64
+}
+
+// sample reports whether to log the next decision.
+func (s *logSampler) sample() bool {
+	n := s.n.Add(1)
+	return uint64(float64(n)*s.rate) != uint64(float64(n-1)*s.rate)
+}
+
+// log logs rec, decided for the request whose context is ctx, per WithLogger.
+func (c *config) log(ctx context.Context, rec AuditRecord) {
+	level := slog.LevelDebug
+	if rec.Err != nil {
+		level = slog.LevelInfo
+	} else if c.logSampler != nil && !c.logSampler.sample() {
+		return
+	}
+	attrs := []slog.Attr{slog.String(AttrOutcome, rec.Outcome.String())}
+	if rec.Source != 0 {
+		attrs = append(attrs, slog.String(AttrSource, rec.Source.String()))
+	}
+	if !rec.Effective.IsZero() {
+		attrs = append(attrs, slog.Time(AttrApplied, rec.Effective),
+			slog.Int64(AttrRemainingMS, rec.Effective.Sub(rec.Time).Milliseconds()))
+	}
+	if rec.Err != nil {
+		attrs = append(attrs, slog.Any("error", rec.Err))
+	}
+	c.logger.LogAttrs(ctx, level, "httpdeadline decision", attrs...)
+}
+
+// LogAttrs returns attributes describing the deadline this package's
+// middleware applied to the request whose context is ctx, with
+// [AttrRemainingMS] measured now, so handlers can annotate their own logs
+// consistently:
+//
+//	logger.LogAttrs(ctx, slog.LevelInfo, "cache miss", httpdeadline.LogAttrs(ctx)...)
+//
+// It returns nil if no deadline was applied.
+func LogAttrs(ctx context.Context) []slog.Attr {
+	a, ok := appliedFrom(ctx)
+	if !ok {
+		return nil
+	}
+	attrs := []slog.Attr{
+		slog.Time(AttrApplied, a.effective),
+		slog.String(AttrOutcome, a.outcome.String()),
+		slog.Int64(AttrRemainingMS, time.Until(a.effective).Milliseconds()),
+	}
+	if a.source != 0 {
+		attrs = append(attrs, slog.String(AttrSource, a.source.String()))
+	}
+	return attrs
+}
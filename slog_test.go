@@ -0,0 +1,202 @@
+package httpdeadline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingHandler is a slog.Handler that retains the records it handles and
+// the contexts they were logged with.
+type recordingHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+	ctxs    []context.Context
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordingHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r.Clone())
+	h.ctxs = append(h.ctxs, ctx)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *recordingHandler) WithGroup(string) slog.Handler      { return h }
+
+func attrsOf(r slog.Record) map[string]slog.Value {
+	attrs := make(map[string]slog.Value)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value
+		return true
+	})
+	return attrs
+}
+
+func TestWithLogger(t *testing.T) {
+	clock := func() time.Time { return now }
+	for _, test := range []struct {
+		Name  string
+		Query bool   // Send Value as a query parameter rather than a header.
+		Value string // Sent only if not empty.
+
+		Level   slog.Level
+		Outcome string
+		Source  string // Empty if AttrSource should be absent.
+		Applied bool
+	}{
+		{Name: "applied", Value: asTimeFormat(now.Add(time.Minute)), Level: slog.LevelDebug, Outcome: "applied", Source: "header", Applied: true},
+		{Name: "query", Query: true, Value: asTimeFormat(now.Add(time.Minute)), Level: slog.LevelDebug, Outcome: "applied", Source: "query", Applied: true},
+		{Name: "rejected", Value: "garbage", Level: slog.LevelInfo, Outcome: "rejected", Source: "header"},
+		{Name: "absent", Level: slog.LevelDebug, Outcome: "absent"},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var rh recordingHandler
+			opts := []Option{WithClock(clock), WithLogger(slog.New(&rh))}
+			var h http.Handler
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.Query {
+				h = FromQueryParams("deadline", new(spyHandler), opts...)
+				req.URL.RawQuery = url.Values{"deadline": {test.Value}}.Encode()
+			} else {
+				h = FromHeader("X-MTP-Deadline", new(spyHandler), opts...)
+				if test.Value != "" {
+					req.Header.Set("X-MTP-Deadline", test.Value)
+				}
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got, want := len(rh.records), 1; got != want {
+				t.Fatalf("len(records) = %v, want %v", got, want)
+			}
+			r := rh.records[0]
+			if got, want := r.Level, test.Level; got != want {
+				t.Errorf("r.Level = %v, want %v", got, want)
+			}
+			attrs := attrsOf(r)
+			if v := attrs[AttrOutcome]; v.Kind() != slog.KindString || v.String() != test.Outcome {
+				t.Errorf("attrs[%q] = %v (%v), want string %q", AttrOutcome, v, v.Kind(), test.Outcome)
+			}
+			if v, ok := attrs[AttrSource]; test.Source == "" && ok {
+				t.Errorf("attrs[%q] = %v, want absent", AttrSource, v)
+			} else if test.Source != "" && (v.Kind() != slog.KindString || v.String() != test.Source) {
+				t.Errorf("attrs[%q] = %v (%v), want string %q", AttrSource, v, v.Kind(), test.Source)
+			}
+			if _, ok := attrs["error"]; ok != (test.Outcome == "rejected") {
+				t.Errorf("attrs[%q] present = %v, want %v", "error", ok, test.Outcome == "rejected")
+			}
+			applied, ok := attrs[AttrApplied]
+			if ok != test.Applied {
+				t.Fatalf("attrs[%q] present = %v, want %v", AttrApplied, ok, test.Applied)
+			}
+			if !test.Applied {
+				return
+			}
+			if got, want := applied, now.Add(time.Minute); applied.Kind() != slog.KindTime || !got.Time().Equal(want) {
+				t.Errorf("attrs[%q] = %v (%v), want time %v", AttrApplied, got, applied.Kind(), want)
+			}
+			if v := attrs[AttrRemainingMS]; v.Kind() != slog.KindInt64 || v.Int64() != 60000 {
+				t.Errorf("attrs[%q] = %v (%v), want int64 60000", AttrRemainingMS, v, v.Kind())
+			}
+		})
+	}
+}
+
+func TestWithLogSampling(t *testing.T) {
+	const n = 1000
+	for _, test := range []struct {
+		Rate float64
+
+		Applied int
+	}{
+		{Rate: 1, Applied: n},
+		{Rate: 0.1, Applied: n / 10},
+		{Rate: 0.25, Applied: n / 4},
+		{Rate: 1.0 / 3, Applied: n / 3},
+	} {
+		t.Run(fmt.Sprint(test.Rate), func(t *testing.T) {
+			var rh recordingHandler
+			h := FromHeader("X-MTP-Deadline", new(spyHandler),
+				WithClock(func() time.Time { return now }),
+				WithLogger(slog.New(&rh)),
+				WithLogSampling(test.Rate))
+			for i := range 2 * n {
+				req := httptest.NewRequest("GET", "/", nil)
+				if i%2 == 0 {
+					req.Header.Set("X-MTP-Deadline", asTimeFormat(now.Add(time.Minute)))
+				} else {
+					req.Header.Set("X-MTP-Deadline", "garbage")
+				}
+				h.ServeHTTP(httptest.NewRecorder(), req)
+			}
+			counts := make(map[string]int)
+			for _, r := range rh.records {
+				counts[attrsOf(r)[AttrOutcome].String()]++
+			}
+			if got, want := counts["applied"], test.Applied; got != want {
+				t.Errorf("applied logs = %v, want %v", got, want)
+			}
+			if got, want := counts["rejected"], n; got != want {
+				t.Errorf("rejected logs = %v, want %v", got, want)
+			}
+		})
+	}
+	for _, rate := range []float64{0, -1, 1.5, math.NaN()} {
+		if _, err := NewPolicy(WithLogSampling(rate)); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("NewPolicy(WithLogSampling(%v)) = %v, want %v", rate, err, ErrInvalidOption)
+		}
+	}
+}
+
+func TestLogAttrs(t *testing.T) {
+	if got := LogAttrs(context.Background()); got != nil {
+		t.Errorf("LogAttrs(context.Background()) = %v, want nil", got)
+	}
+	deadline := time.Now().Add(time.Minute).Truncate(time.Second)
+	var attrs map[string]slog.Value
+	h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var r slog.Record
+		r.AddAttrs(LogAttrs(req.Context())...)
+		attrs = attrsOf(r)
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-MTP-Deadline", asTimeFormat(deadline))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if v := attrs[AttrApplied]; v.Kind() != slog.KindTime || !v.Time().Equal(deadline) {
+		t.Errorf("attrs[%q] = %v (%v), want time %v", AttrApplied, v, v.Kind(), deadline)
+	}
+	if v := attrs[AttrOutcome]; v.Kind() != slog.KindString || v.String() != "applied" {
+		t.Errorf("attrs[%q] = %v (%v), want string %q", AttrOutcome, v, v.Kind(), "applied")
+	}
+	if v := attrs[AttrSource]; v.Kind() != slog.KindString || v.String() != "header" {
+		t.Errorf("attrs[%q] = %v (%v), want string %q", AttrSource, v, v.Kind(), "header")
+	}
+	if v := attrs[AttrRemainingMS]; v.Kind() != slog.KindInt64 || v.Int64() <= 0 || v.Int64() > 60000 {
+		t.Errorf("attrs[%q] = %v (%v), want int64 in (0, 60000]", AttrRemainingMS, v, v.Kind())
+	}
+}
+
+func TestWithLoggerContext(t *testing.T) {
+	type traceKey struct{}
+	var rh recordingHandler
+	h := FromHeader("X-MTP-Deadline", new(spyHandler), WithLogger(slog.New(&rh)))
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), traceKey{}, "trace"))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if got, want := len(rh.ctxs), 1; got != want {
+		t.Fatalf("len(ctxs) = %v, want %v", got, want)
+	}
+	if got, want := rh.ctxs[0].Value(traceKey{}), "trace"; got != want {
+		t.Errorf("logged with context value %v, want %v", got, want)
+	}
+}
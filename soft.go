@@ -0,0 +1,35 @@
+package httpdeadline
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// WithSoftDeadline calls fn (on its own goroutine) offset before the deadline
+// applied to a request, so the handler can start winding down (e.g., stop
+// accepting new work and flush partial results) while the deadline itself
+// still cancels the request's context.  fn is not called if the handler
+// returns first.  If less than offset remains when the request arrives, fn is
+// called right away.  [SoftDeadline] reports when fn is due, and [Deadline]
+// continues to report the (hard) deadline.
+func WithSoftDeadline(offset time.Duration, fn func(*http.Request)) Option {
+	if offset <= 0 {
+		return invalidf("non-positive soft deadline offset")
+	}
+	if fn == nil {
+		return invalidf("nil soft deadline func")
+	}
+	return optionFunc(func(c *config) { c.softOffset, c.onSoftDeadline = offset, fn })
+}
+
+// SoftDeadline reports the soft deadline (see [WithSoftDeadline]) of the request
+// whose context is ctx.  It reports false if none was configured or no
+// deadline was applied.
+func SoftDeadline(ctx context.Context) (time.Time, bool) {
+	a, ok := appliedFrom(ctx)
+	if !ok || a.soft.IsZero() {
+		return time.Time{}, false
+	}
+	return a.soft, true
+}
@@ -0,0 +1,55 @@
+package httpdeadline
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithSoftDeadline(t *testing.T) {
+	t.Run("fires", func(t *testing.T) {
+		var softAt, hardAt time.Time
+		fired := make(chan time.Time, 1)
+		deadline := time.Now().Add(150 * time.Millisecond)
+		h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			soft, ok := SoftDeadline(req.Context())
+			if got, want := soft, deadline.Add(-100*time.Millisecond); !ok || !got.Equal(want) {
+				t.Errorf("SoftDeadline(ctx) = %v, %v; want %v, true", got, ok, want)
+			}
+			if hard, _ := Deadline(req.Context()); !hard.Equal(deadline) {
+				t.Errorf("Deadline(ctx) = %v, want %v", hard, deadline)
+			}
+			<-req.Context().Done()
+			hardAt = time.Now()
+			softAt = <-fired
+		}), WithLayout(time.RFC3339Nano), WithSoftDeadline(100*time.Millisecond, func(*http.Request) {
+			fired <- time.Now()
+		}))
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-MTP-Deadline", deadline.Format(time.RFC3339Nano))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if !softAt.Before(hardAt) {
+			t.Errorf("soft deadline fired at %v, not before hard cancellation at %v", softAt, hardAt)
+		}
+	})
+	t.Run("suppressed", func(t *testing.T) {
+		var fired atomic.Bool
+		h := FromHeader("X-MTP-Deadline", new(spyHandler), WithLayout(time.RFC3339Nano),
+			WithSoftDeadline(100*time.Millisecond, func(*http.Request) { fired.Store(true) }))
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-MTP-Deadline", time.Now().Add(150*time.Millisecond).Format(time.RFC3339Nano))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		time.Sleep(100 * time.Millisecond)
+		if fired.Load() {
+			t.Error("soft deadline func called after handler returned")
+		}
+	})
+	t.Run("unconfigured", func(t *testing.T) {
+		if _, ok := SoftDeadline(context.Background()); ok {
+			t.Error("SoftDeadline(context.Background()) reported true")
+		}
+	})
+}
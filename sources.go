@@ -0,0 +1,168 @@
+package httpdeadline
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// An Extractor is one step of the fallback chain of deadline sources that
+// [Policy.WithSources] configures.  Create them with [HeaderSource],
+// [QuerySource], [CookieSource], and [DefaultSource].
+type Extractor interface {
+	// extract finds the raw deadline in req.  It reports false if req carries
+	// none, so that the chain falls through to the next step.
+	extract(c *config, req *http.Request) (val string, ok bool, err error)
+	source() Source
+}
+
+type headerSource string
+
+func (s headerSource) extract(_ *config, req *http.Request) (string, bool, error) {
+	vals, ok := req.Header[http.CanonicalHeaderKey(string(s))]
+	if !ok {
+		return "", false, nil
+	}
+	return vals[0], true, nil
+}
+
+func (headerSource) source() Source { return SourceHeader }
+
+// HeaderSource finds the deadline in the named HTTP header, like [FromHeader].
+func HeaderSource(name string) Extractor { return headerSource(name) }
+
+type querySource string
+
+func (s querySource) extract(c *config, req *http.Request) (string, bool, error) {
+	return c.lookupQuery(req, string(s))
+}
+
+func (querySource) source() Source { return SourceQuery }
+
+// QuerySource finds the deadline in the named query parameter, like
+// [FromQueryParams].
+func QuerySource(name string) Extractor { return querySource(name) }
+
+type cookieSource string
+
+func (s cookieSource) extract(_ *config, req *http.Request) (string, bool, error) {
+	cookie, err := req.Cookie(string(s))
+	if errors.Is(err, http.ErrNoCookie) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", true, err
+	}
+	return cookie.Value, true, nil
+}
+
+func (cookieSource) source() Source { return SourceCookie }
+
+// CookieSource finds the deadline in the named cookie.  Its layouts may be
+// scoped with [WithSourceLayout] and [SourceCookie].
+func CookieSource(name string) Extractor { return cookieSource(name) }
+
+type defaultSource time.Duration
+
+func (defaultSource) extract(*config, *http.Request) (string, bool, error) { return "", false, nil }
+
+func (defaultSource) source() Source { return 0 }
+
+// DefaultSource ends the chain with a server default deadline d from when the
+// request is received, like [WithDefaultDeadline], except that the
+// [Policy]'s caps apply to it too.  It must be the last step.
+func DefaultSource(d time.Duration) Extractor { return defaultSource(d) }
+
+// WithSources returns a copy of p whose handlers, created with
+// [Policy.Handler], find the deadline by trying srcs in order:
+//
+//	policy, err := httpdeadline.NewPolicy(httpdeadline.WithMaxDeadline(2 * time.Minute))
+//	// ...
+//	policy, err = policy.WithSources(
+//		httpdeadline.HeaderSource("X-MTP-Deadline"),
+//		httpdeadline.QuerySource("deadline"),
+//		httpdeadline.CookieSource("deadline"),
+//		httpdeadline.DefaultSource(30*time.Second),
+//	)
+//	// ...
+//	h := policy.Handler(teapotz)
+//
+// The first step whose source is present in the request is authoritative:
+// its value is validated and capped per p as usual, and a malformed value is
+// rejected rather than falling through to later steps.  Requests in which no
+// source is present receive the default of a final [DefaultSource], which
+// supersedes p's own [WithDefaultDeadline] or [WithDefaultDeadlineFunc];
+// without one, p's default (including [WithMaxAsDefault]) applies as usual.
+// Only if there is neither do such requests pass through unbounded.
+//
+// Like [NewPolicy], WithSources reports every problem among srcs (e.g., a
+// DefaultSource that is not last) in its error, each matching
+// [ErrInvalidOption].
+//
+// Only [Policy.Handler] honors the sources; passing the returned Policy as an
+// [Option] to the other handler constructors is misconfigured.
+func (p *Policy) WithSources(srcs ...Extractor) (*Policy, error) {
+	pp := &Policy{cfg: p.cfg.clone()}
+	var errs []error
+	for i, src := range srcs {
+		switch src := src.(type) {
+		case nil:
+			errs = append(errs, fmt.Errorf("%w: nil Extractor", ErrInvalidOption))
+			continue
+		case defaultSource:
+			if i != len(srcs)-1 {
+				errs = append(errs, fmt.Errorf("%w: DefaultSource is not the last source", ErrInvalidOption))
+				continue
+			}
+			pp.cfg.defaultDeadline, pp.cfg.defaultFixed = pp.cfg.cappedDefault(time.Duration(src)), false
+			continue
+		}
+		pp.sources = append(pp.sources, src)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return pp, nil
+}
+
+// cappedDefault returns a default deadline func for d that respects c's caps.
+func (c *config) cappedDefault(d time.Duration) func(*http.Request) time.Duration {
+	maxDeadline, adaptiveCap := c.maxDeadline, c.adaptiveCap
+	return func(req *http.Request) time.Duration {
+		budget := d
+		if maxDeadline != nil {
+			if limit := maxDeadline(req); limit > 0 {
+				budget = min(budget, limit)
+			}
+		}
+		if adaptiveCap != nil {
+			if limit := adaptiveCap(); limit > 0 {
+				budget = min(budget, limit)
+			}
+		}
+		return budget
+	}
+}
+
+// Handler wraps the provided [http.Handler] in an outer http.Handler that sets
+// a maximum deadline on the [http.Request]'s context per p, from the sources
+// that [Policy.WithSources] configured.  Without sources, only defaults apply.
+func (p *Policy) Handler(h http.Handler) http.Handler {
+	return &handler{
+		cfg:   p.cfg.clone(),
+		chain: p.sources,
+		next:  h,
+	}
+}
+
+// lookupChain finds the raw deadline value in req by trying chain in order.
+func (c *config) lookupChain(req *http.Request, chain []Extractor) (string, Source, bool, error) {
+	for _, e := range chain {
+		val, ok, err := e.extract(c, req)
+		if ok || err != nil {
+			return val, e.source(), true, err
+		}
+	}
+	return "", 0, false, nil
+}
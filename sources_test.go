@@ -0,0 +1,149 @@
+package httpdeadline
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestPolicyWithSources(t *testing.T) {
+	var got AuditRecord
+	policy, err := NewPolicy(
+		WithClock(func() time.Time { return now }),
+		WithLayout(time.RFC3339),
+		WithMaxDeadline(2*time.Minute),
+		WithAuditSink(func(rec AuditRecord) { got = rec }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, err = policy.WithSources(
+		HeaderSource("X-MTP-Deadline"),
+		QuerySource("deadline"),
+		CookieSource("deadline"),
+		DefaultSource(30*time.Second),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(d time.Duration) *string { return ptr(now.Add(d).Format(time.RFC3339)) }
+	for _, test := range []struct {
+		Name   string
+		Header *string
+		Query  *string
+		Cookie *string
+
+		Status   int
+		Deadline time.Time
+		Outcome  Outcome
+	}{
+		{Name: "header", Header: at(time.Minute), Query: at(10 * time.Second), Status: 200, Deadline: now.Add(time.Minute), Outcome: OutcomeApplied},
+		{Name: "query", Query: at(50 * time.Second), Cookie: at(10 * time.Second), Status: 200, Deadline: now.Add(50 * time.Second), Outcome: OutcomeApplied},
+		{Name: "cookie", Cookie: at(40 * time.Second), Status: 200, Deadline: now.Add(40 * time.Second), Outcome: OutcomeApplied},
+		{Name: "default", Status: 200, Deadline: now.Add(30 * time.Second), Outcome: OutcomeDefault},
+		{Name: "header-clamped", Header: at(time.Hour), Status: 200, Deadline: now.Add(2 * time.Minute), Outcome: OutcomeClamped},
+		{Name: "cookie-clamped", Cookie: at(time.Hour), Status: 200, Deadline: now.Add(2 * time.Minute), Outcome: OutcomeClamped},
+		{Name: "header-malformed", Header: ptr("garbage"), Query: at(time.Minute), Status: 400, Outcome: OutcomeRejected},
+		{Name: "query-empty", Query: ptr(""), Cookie: at(time.Minute), Status: 400, Outcome: OutcomeRejected},
+		{Name: "cookie-malformed", Cookie: ptr("garbage"), Status: 400, Outcome: OutcomeRejected},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var spy spyHandler
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.Header != nil {
+				req.Header.Set("X-MTP-Deadline", *test.Header)
+			}
+			if test.Query != nil {
+				req.URL.RawQuery = url.Values{"deadline": {*test.Query}}.Encode()
+			}
+			if test.Cookie != nil {
+				req.AddCookie(&http.Cookie{Name: "deadline", Value: *test.Cookie})
+			}
+			rec := httptest.NewRecorder()
+			policy.Handler(&spy).ServeHTTP(rec, req)
+			if got, want := rec.Code, test.Status; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+			if got, want := got.Outcome, test.Outcome; got != want {
+				t.Errorf("rec.Outcome = %v, want %v", got, want)
+			}
+			if got, want := spy.Deadline, test.Deadline; !got.Equal(want) {
+				t.Errorf("spy.Deadline = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDefaultSourceCapped(t *testing.T) {
+	policy, err := NewPolicy(WithClock(func() time.Time { return now }), WithMaxDeadline(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, err = policy.WithSources(HeaderSource("X-MTP-Deadline"), DefaultSource(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spy spyHandler
+	policy.Handler(&spy).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if got, want := spy.Deadline, now.Add(time.Minute); !got.Equal(want) {
+		t.Errorf("spy.Deadline = %v, want %v", got, want)
+	}
+}
+
+func TestPolicyWithSourcesPolicyDefault(t *testing.T) {
+	policy, err := NewPolicy(WithClock(func() time.Time { return now }), WithDefaultDeadline(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, err = policy.WithSources(HeaderSource("X-MTP-Deadline"), QuerySource("deadline"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spy spyHandler
+	policy.Handler(&spy).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if got, want := spy.Deadline, now.Add(time.Minute); !spy.OK || !got.Equal(want) {
+		t.Errorf("spy.Deadline = %v, %v; want %v", got, spy.OK, want)
+	}
+}
+
+func TestPolicyWithSourcesInvalid(t *testing.T) {
+	for _, test := range []struct {
+		Name    string
+		Sources []Extractor
+	}{
+		{Name: "nil", Sources: []Extractor{nil}},
+		{Name: "default-not-last", Sources: []Extractor{DefaultSource(time.Second), HeaderSource("X-MTP-Deadline")}},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			if _, err := new(Policy).WithSources(test.Sources...); !errors.Is(err, ErrInvalidOption) {
+				t.Errorf("WithSources() = _, %v; want %v", err, ErrInvalidOption)
+			}
+		})
+	}
+}
+
+func TestPolicyWithSourcesAsOption(t *testing.T) {
+	policy, err := new(Policy).WithSources(QuerySource("deadline"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPolicy(policy); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("NewPolicy(policy) = _, %v; want %v", err, ErrInvalidOption)
+	}
+}
+
+func TestPolicyHandlerWithoutSources(t *testing.T) {
+	policy, err := NewPolicy(WithClock(func() time.Time { return now }), WithDefaultDeadline(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spy spyHandler
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-MTP-Deadline", asTimeFormat(now.Add(time.Hour)))
+	policy.Handler(&spy).ServeHTTP(httptest.NewRecorder(), req)
+	if got, want := spy.Deadline, now.Add(time.Minute); !got.Equal(want) {
+		t.Errorf("spy.Deadline = %v, want %v", got, want)
+	}
+}
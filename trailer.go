@@ -0,0 +1,52 @@
+package httpdeadline
+
+import (
+	"context"
+	"net/http"
+)
+
+// WithDeadlineTrailer lets handlers opt into reporting the deadline applied to
+// their request in the named HTTP trailer (in the format of
+// [WithEmitFormat]), for clients that want to know what budget the server
+// honored.  Handlers opt in at runtime with [EnableDeadlineTrailer]; responses
+// of the rest carry no trailers.  Since HTTP/1.1 responses need the trailer
+// declared in their header, handlers should opt in before writing; the
+// middleware then sends such responses chunked.  Trailers of handlers opting
+// in later are sent only if the response was chunked anyway (e.g., because
+// the handler flushed it), as well as over HTTP/2 and later.
+func WithDeadlineTrailer(name string) Option {
+	if name == "" {
+		return invalidf("empty deadline trailer name")
+	}
+	return optionFunc(func(c *config) { c.deadlineTrailer = name })
+}
+
+// EnableDeadlineTrailer opts the response to the request whose context is ctx
+// into the trailer that [WithDeadlineTrailer] configures.  It reports false if
+// the middleware will not send one, because it applied no deadline or was not
+// configured to.  It may be called from any goroutine before the handler
+// returns.
+func EnableDeadlineTrailer(ctx context.Context) bool {
+	a, ok := appliedFrom(ctx)
+	if !ok || a.trailer == "" {
+		return false
+	}
+	a.trailerEnabled.Store(true)
+	return true
+}
+
+// declareTrailer declares a's deadline trailer in the response header h if the
+// handler opted in before the header was written, which HTTP/1.1 responses
+// need in order to be sent chunked.
+func (a *applied) declareTrailer(h http.Header) {
+	if a.trailerEnabled.Load() {
+		h.Add("Trailer", a.trailer)
+	}
+}
+
+// setDeadlineTrailer sets a's deadline trailer on w if the handler opted in.
+func (c *config) setDeadlineTrailer(w http.ResponseWriter, a *applied) {
+	if a.trailerEnabled.Load() {
+		w.Header().Set(http.TrailerPrefix+a.trailer, c.format(a.effective))
+	}
+}
@@ -0,0 +1,66 @@
+package httpdeadline
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWithDeadlineTrailer(t *testing.T) {
+	deadline := time.Now().Add(time.Hour).Truncate(time.Second)
+	for _, test := range []struct {
+		Name   string
+		OptIn  bool
+		Header string
+		Silent bool
+
+		Enabled bool
+		Trailer string
+	}{
+		{Name: "opt-in", OptIn: true, Header: asTimeFormat(deadline), Enabled: true, Trailer: asTimeFormat(deadline)},
+		{Name: "no-opt-in", OptIn: false, Header: asTimeFormat(deadline)},
+		{Name: "opt-in-unwritten", OptIn: true, Header: asTimeFormat(deadline), Silent: true, Enabled: true, Trailer: asTimeFormat(deadline)},
+		{Name: "opt-in-absent", OptIn: true},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var enabled bool
+			srv := newServer(t, FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if test.OptIn {
+					enabled = EnableDeadlineTrailer(req.Context())
+				}
+				if !test.Silent {
+					io.WriteString(w, "tea")
+				}
+			}), WithDeadlineTrailer("X-MTP-Deadline-Applied")))
+			req := newGetRequest(t, urlOf(t, srv))
+			if test.Header != "" {
+				req.Header.Set("X-MTP-Deadline", test.Header)
+			}
+			resp, err := newClient().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if _, err := io.ReadAll(resp.Body); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := enabled, test.Enabled; got != want {
+				t.Errorf("EnableDeadlineTrailer() = %v, want %v", got, want)
+			}
+			if got, want := resp.Trailer.Get("X-MTP-Deadline-Applied"), test.Trailer; got != want {
+				t.Errorf("trailer = %q, want %q", got, want)
+			}
+			if !test.Enabled && len(resp.Trailer) != 0 {
+				t.Errorf("resp.Trailer = %v, want none", resp.Trailer)
+			}
+		})
+	}
+}
+
+func TestEnableDeadlineTrailerUnconfigured(t *testing.T) {
+	if EnableDeadlineTrailer(context.Background()) {
+		t.Error("EnableDeadlineTrailer(context.Background()) = true, want false")
+	}
+}
@@ -0,0 +1,41 @@
+package httpdeadline
+
+import (
+	"net/http"
+	"slices"
+	"time"
+)
+
+// WithBypassMethods exempts requests with the given methods (e.g.,
+// [http.MethodConnect]) from deadline handling.  They pass through untouched,
+// like requests without deadlines, except that no default applies.
+//
+// By default, CONNECT requests are treated like any other, so a client's
+// deadline bounds the tunnel a proxy establishes for them and tears it down
+// when it passes.  Bypass CONNECT, or bound tunnels separately with
+// [WithTunnelDeadline], where that is unwanted.
+func WithBypassMethods(methods ...string) Option {
+	for _, method := range methods {
+		if method == "" {
+			return invalidf("empty bypass method")
+		}
+	}
+	return optionFunc(func(c *config) { c.bypassMethods = append(c.bypassMethods, methods...) })
+}
+
+// WithTunnelDeadline applies a deadline d from when the request is received
+// to CONNECT requests in place of whatever deadline the client sent, so that
+// the tunnels they establish outlive typical request deadlines yet remain
+// bounded.  It is reported as [OutcomeDefault].  [WithBypassMethods] takes
+// precedence.
+func WithTunnelDeadline(d time.Duration) Option {
+	if d <= 0 {
+		return invalidf("non-positive tunnel deadline")
+	}
+	return optionFunc(func(c *config) { c.tunnelDeadline = d })
+}
+
+// bypasses reports whether req is exempt from deadline handling.
+func (c *config) bypasses(req *http.Request) bool {
+	return slices.Contains(c.bypassMethods, req.Method)
+}
@@ -0,0 +1,55 @@
+package httpdeadline
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTunnels(t *testing.T) {
+	for _, test := range []struct {
+		Name   string
+		Method string
+		Opts   []Option
+
+		Deadline time.Time
+		Outcome  Outcome
+	}{
+		{Name: "default", Method: "CONNECT", Deadline: now.Add(time.Second), Outcome: OutcomeApplied},
+		{Name: "bypass", Method: "CONNECT", Opts: []Option{WithBypassMethods("CONNECT")}, Outcome: OutcomeAbsent},
+		{Name: "bypass-other", Method: "GET", Opts: []Option{WithBypassMethods("CONNECT")}, Deadline: now.Add(time.Second), Outcome: OutcomeApplied},
+		{Name: "tunnel", Method: "CONNECT", Opts: []Option{WithTunnelDeadline(time.Hour)}, Deadline: now.Add(time.Hour), Outcome: OutcomeDefault},
+		{Name: "tunnel-other", Method: "GET", Opts: []Option{WithTunnelDeadline(time.Hour)}, Deadline: now.Add(time.Second), Outcome: OutcomeApplied},
+		{Name: "bypass-and-tunnel", Method: "CONNECT", Opts: []Option{WithBypassMethods("CONNECT"), WithTunnelDeadline(time.Hour)}, Outcome: OutcomeAbsent},
+		{Name: "bypass-default", Method: "CONNECT", Opts: []Option{WithBypassMethods("CONNECT"), WithDefaultDeadline(time.Minute)}, Outcome: OutcomeAbsent},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var (
+				spy spyHandler
+				got AuditRecord
+			)
+			h := FromHeader("X-MTP-Deadline", &spy, append(test.Opts,
+				WithClock(func() time.Time { return now }),
+				WithAuditSink(func(rec AuditRecord) { got = rec }))...)
+			req := httptest.NewRequest(test.Method, "example.com:443", nil)
+			req.Header.Set("X-MTP-Deadline", asTimeFormat(now.Add(time.Second)))
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got, want := spy.OK, !test.Deadline.IsZero(); got != want {
+				t.Fatalf("spy.OK = %v, want %v", got, want)
+			}
+			if got, want := spy.Deadline, test.Deadline; spy.OK && !got.Equal(want) {
+				t.Errorf("spy.Deadline = %v, want %v", got, want)
+			}
+			if got, want := got.Outcome, test.Outcome; got != want {
+				t.Errorf("rec.Outcome = %v, want %v", got, want)
+			}
+		})
+	}
+	if _, err := NewPolicy(WithBypassMethods("")); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("NewPolicy(WithBypassMethods(\"\")) = %v, want %v", err, ErrInvalidOption)
+	}
+	if _, err := NewPolicy(WithTunnelDeadline(0)); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("NewPolicy(WithTunnelDeadline(0)) = %v, want %v", err, ErrInvalidOption)
+	}
+}
@@ -0,0 +1,39 @@
+package httpdeadline
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// ValidateValue reports whether the middleware configured with opts would
+// accept value as the deadline header of [FromHeader], without involving an HTTP
+// server, for instance to preflight values in command-line tools.  It returns
+// nil if value would be honored (perhaps after capping) or the same classified
+// error that the middleware would reject the request with (e.g., one matching
+// [ErrParse]).  Misconfigured opts are reported with errors matching
+// [ErrInvalidOption], as with [NewPolicy].  Validation leaves no trace: it
+// draws nothing from a [BudgetBucket], counts nothing for [WithFormatMetrics],
+// and does not count toward [WithExpiredDeadlineRateLimit].
+//
+// Options that inspect the request (e.g., [WithMaxDeadlineFunc] or
+// [WithTrustedSource]) see a bare GET request for "/" carrying only value.
+func ValidateValue(value string, opts ...Option) error {
+	cfg := newConfig(opts)
+	if err := errors.Join(cfg.errs...); err != nil {
+		return err
+	}
+	const name = "X-Deadline"
+	h := &handler{
+		cfg:    cfg,
+		lookup: func(req *http.Request) (string, bool, error) { return req.Header.Get(name), true, nil },
+		source: SourceHeader,
+		dryRun: true,
+	}
+	req := &http.Request{
+		Method: "GET",
+		URL:    &url.URL{Path: "/"},
+		Header: http.Header{name: {value}},
+	}
+	return h.current().decide(req).Err
+}
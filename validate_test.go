@@ -0,0 +1,73 @@
+package httpdeadline
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestValidateValue(t *testing.T) {
+	clock := WithClock(func() time.Time { return now })
+	for _, test := range []struct {
+		Name  string
+		Value string
+		Opts  []Option
+
+		Err error
+	}{
+		{Name: "valid", Value: asTimeFormat(now.Add(time.Second))},
+		{Name: "rfc850", Value: asRFC850(now.Add(time.Second))},
+		{Name: "clamped", Value: asTimeFormat(now.Add(time.Hour)), Opts: []Option{WithMaxDeadline(time.Minute)}},
+		{Name: "empty", Value: "", Err: ErrEmptyValue},
+		{Name: "malformed", Value: "garbage", Err: ErrParse},
+		{Name: "layout-not-accepted", Value: now.Format(time.RFC3339), Err: ErrParse},
+		{Name: "layout-accepted", Value: now.Add(time.Second).Format(time.RFC3339), Opts: []Option{WithLayout(time.RFC3339)}},
+		{Name: "expired", Value: asTimeFormat(now.Add(-time.Second)), Opts: []Option{WithRejectExpired()}, Err: ErrDeadlineExpired},
+		{Name: "too-small", Value: asTimeFormat(now.Add(time.Second)), Opts: []Option{WithMinimumServiceTime(func(*http.Request) time.Duration { return time.Minute })}, Err: ErrBudgetTooSmall},
+		{Name: "duration", Value: "2m30s", Opts: []Option{WithDurationValues()}},
+		{Name: "duration-negative", Value: "-5s", Opts: []Option{WithDurationValues()}, Err: ErrNonPositiveBudget},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			opts := append([]Option{clock}, test.Opts...)
+			err := ValidateValue(test.Value, opts...)
+			if !errors.Is(err, test.Err) || (test.Err == nil) != (err == nil) {
+				t.Errorf("ValidateValue(%q) = %v, want %v", test.Value, err, test.Err)
+			}
+
+			var rec AuditRecord
+			h := FromHeader("X-MTP-Deadline", new(spyHandler), append(opts, WithAuditSink(func(r AuditRecord) { rec = r }))...)
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-MTP-Deadline", test.Value)
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got, want := err == nil, rec.Outcome != OutcomeRejected; got != want {
+				t.Errorf("ValidateValue accepted = %v, FromHeader accepted = %v", got, want)
+			}
+			if rec.Err != nil && err != nil && rec.Err.Error() != err.Error() {
+				t.Errorf("ValidateValue err = %v, FromHeader err = %v", err, rec.Err)
+			}
+		})
+	}
+	if err := ValidateValue("", WithMaxDeadlineFunc(nil)); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("ValidateValue with misconfigured option = %v, want %v", err, ErrInvalidOption)
+	}
+}
+
+func TestValidateValueNoSideEffects(t *testing.T) {
+	b := NewBudgetBucket(time.Minute, time.Second)
+	before := formatCount("TimeFormat")
+	opts := []Option{WithClock(func() time.Time { return now }), WithBudgetRateLimit(b), WithFormatMetrics()}
+	if err := ValidateValue(asTimeFormat(now.Add(time.Second)), opts...); err != nil {
+		t.Fatalf("ValidateValue = %v, want nil", err)
+	}
+	if err := ValidateValue(asTimeFormat(now.Add(time.Hour)), opts...); err != nil {
+		t.Fatalf("ValidateValue = %v, want nil", err)
+	}
+	if got, want := b.take(now, time.Hour), time.Minute; got != want {
+		t.Errorf("bucket level after ValidateValue = %v, want %v", got, want)
+	}
+	if got, want := formatCount("TimeFormat"), before; got != want {
+		t.Errorf("format count after ValidateValue = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,118 @@
+package httpdeadline
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// lookupValues finds the named deadline in values the way [FromQueryParams]
+// finds it in the query.
+func lookupValues(values url.Values, name string) (string, bool, error) {
+	if !values.Has(name) {
+		return "", false, nil
+	}
+	return values.Get(name), true, nil
+}
+
+// WithQueryPlusLiteral makes [FromQueryParams] treat "+" in the raw query
+// value of its parameter as a literal plus sign rather than a space.  By
+// default, the value is decoded like [url.URL.Query] decodes it: both "+" and
+// "%20" decode to a space and "%2B" to a plus sign, which breaks timestamps
+// whose numeric zone offsets clients neglect to escape (e.g.,
+// "2024-07-22T22:10:00+02:00").  With this option, "%20" still decodes to a
+// space and "%2B" to a plus sign, but "+" stays a plus sign.  It has no
+// effect on other sources, nor on [ApplyFromValues], whose values are
+// already decoded.
+func WithQueryPlusLiteral() Option {
+	return optionFunc(func(c *config) { c.queryPlusLiteral = true })
+}
+
+// lookupRawQuery finds the named value in the raw query like lookupValues
+// does in the decoded query, except that it keeps "+" in the value literal.
+func lookupRawQuery(rawQuery, name string) (string, bool, error) {
+	for rawQuery != "" {
+		var pair string
+		pair, rawQuery, _ = strings.Cut(rawQuery, "&")
+		rawKey, rawVal, _ := strings.Cut(pair, "=")
+		if key, err := url.QueryUnescape(rawKey); err != nil || key != name {
+			continue
+		}
+		val, err := url.PathUnescape(rawVal)
+		return val, true, err
+	}
+	return "", false, nil
+}
+
+// lookupQuery finds the named value in req's query per c.
+func (c *config) lookupQuery(req *http.Request, name string) (string, bool, error) {
+	if c.queryPlusLiteral {
+		return lookupRawQuery(req.URL.RawQuery, name)
+	}
+	return lookupValues(req.URL.Query(), name)
+}
+
+// lookupValue finds the raw deadline value in req and reports its source.
+func (h *handler) lookupValue(req *http.Request) (string, Source, bool, error) {
+	if h.lookup == nil {
+		return h.cfg.lookupChain(req, h.chain)
+	}
+	if h.queryParam != "" && h.cfg.queryPlusLiteral {
+		val, ok, err := lookupRawQuery(req.URL.RawQuery, h.queryParam)
+		return val, SourceQuery, ok, err
+	}
+	val, ok, err := h.lookup(req)
+	return val, h.source, ok, err
+}
+
+// ApplyFromValues applies the deadline named name in values, which the caller
+// already parsed (e.g., with [http.Request.ParseForm]), to req, sparing
+// handler stacks that parsed the query anyway a second parse by
+// [FromQueryParams].  The value is parsed and validated exactly as
+// FromQueryParams would, and audit sinks see the decision.  It returns req
+// unchanged if values carries no deadline (and no default applies), and
+// otherwise a shallow copy carrying the deadline, along with a CancelFunc that
+// the caller must call once done with the request.  Values that
+// FromQueryParams would reject are reported with the same classified error
+// (e.g., one matching [ErrParse]), and misconfigured opts with errors matching
+// [ErrInvalidOption].
+//
+// Options that act while the handler runs, like [WithStreamingMode],
+// [WithSoftDeadline], or [WithScheduler], have no effect here.  With
+// [WithAdvisoryOnly], the copy carries the deadline only as metadata, for
+// [Deadline] to report, and the CancelFunc does nothing.  Construct opts
+// once with [NewPolicy] to avoid configuring them anew on every call.
+func ApplyFromValues(values url.Values, name string, req *http.Request, opts ...Option) (*http.Request, context.CancelFunc, error) {
+	cfg := newConfig(opts)
+	if err := errors.Join(cfg.errs...); err != nil {
+		return req, func() {}, err
+	}
+	h := (&handler{
+		cfg:    cfg,
+		lookup: func(*http.Request) (string, bool, error) { return lookupValues(values, name) },
+		source: SourceQuery,
+	}).current()
+	rec := h.decide(req)
+	h.cfg.audit(req.Context(), rec)
+	switch rec.Outcome {
+	case OutcomeAbsent, OutcomeUnbounded:
+		return req, func() {}, nil
+	case OutcomeRejected:
+		return req, func() {}, rec.Err
+	}
+	ctx := withApplied(req.Context(), &applied{
+		effective:   rec.Effective,
+		requested:   rec.Requested,
+		value:       rec.Value,
+		source:      rec.Source,
+		outcome:     rec.Outcome,
+		uncancelled: h.cfg.advisory,
+	})
+	if h.cfg.advisory {
+		return req.WithContext(ctx), func() {}, nil
+	}
+	ctx, cancel := context.WithDeadlineCause(ctx, rec.Effective, ErrDeadlineExceeded)
+	return req.WithContext(ctx), cancel, nil
+}
@@ -0,0 +1,199 @@
+package httpdeadline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyFromValues(t *testing.T) {
+	opts := []Option{WithClock(func() time.Time { return now }), WithMaxDeadline(time.Hour)}
+	for _, test := range []struct {
+		Name  string
+		Query string
+	}{
+		{Name: "absent", Query: "q=earl+grey"},
+		{Name: "valid", Query: "deadline=" + url.QueryEscape(asTimeFormat(now.Add(time.Minute)))},
+		{Name: "clamped", Query: "deadline=" + url.QueryEscape(asTimeFormat(now.Add(2*time.Hour)))},
+		{Name: "empty", Query: "deadline="},
+		{Name: "invalid", Query: "deadline=garbage"},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var spy spyHandler
+			rec := httptest.NewRecorder()
+			FromQueryParams("deadline", &spy, opts...).ServeHTTP(rec, httptest.NewRequest("GET", "/?"+test.Query, nil))
+			rejected := rec.Code == http.StatusBadRequest
+
+			req := httptest.NewRequest("GET", "/?"+test.Query, nil)
+			if err := req.ParseForm(); err != nil {
+				t.Fatal(err)
+			}
+			got, cancel, err := ApplyFromValues(req.Form, "deadline", req, opts...)
+			defer cancel()
+			if (err != nil) != rejected {
+				t.Fatalf("ApplyFromValues() = _, _, %v; want error %v", err, rejected)
+			}
+			if rejected {
+				return
+			}
+			deadline, ok := Deadline(got.Context())
+			if !deadline.Equal(spy.Deadline) || ok != spy.OK {
+				t.Errorf("Deadline() = %v, %v; want %v, %v", deadline, ok, spy.Deadline, spy.OK)
+			}
+		})
+	}
+}
+
+func TestApplyFromValuesErrors(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	for _, test := range []struct {
+		Name   string
+		Values url.Values
+		Opts   []Option
+
+		Err error
+	}{
+		{Name: "invalid", Values: url.Values{"deadline": {"garbage"}}, Err: ErrParse},
+		{Name: "empty", Values: url.Values{"deadline": {""}}, Err: ErrEmptyValue},
+		{Name: "misconfigured", Opts: []Option{WithLayout("")}, Err: ErrInvalidOption},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			got, cancel, err := ApplyFromValues(test.Values, "deadline", req, test.Opts...)
+			defer cancel()
+			if !errors.Is(err, test.Err) {
+				t.Errorf("ApplyFromValues() = _, _, %v; want %v", err, test.Err)
+			}
+			if got != req {
+				t.Error("ApplyFromValues() returned a different request on error")
+			}
+		})
+	}
+}
+
+func TestApplyFromValuesCause(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	values := url.Values{"deadline": {time.Now().Add(-time.Second).Format(time.RFC3339Nano)}}
+	got, cancel, err := ApplyFromValues(values, "deadline", req, WithLayout(time.RFC3339Nano))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+	<-got.Context().Done()
+	if got, want := context.Cause(got.Context()), ErrDeadlineExceeded; got != want {
+		t.Errorf("context.Cause() = %v, want %v", got, want)
+	}
+}
+
+func TestWithQueryPlusLiteral(t *testing.T) {
+	deadline := now.Add(time.Hour)
+	rfc3339 := deadline.In(time.FixedZone("", 2*60*60)).Format(time.RFC3339) // "...T22:10:00+02:00"
+	spaced := asTimeFormat(deadline)                                         // "Mon, 22 Jul 2024 21:10:00 GMT"
+	for _, test := range []struct {
+		Name     string
+		RawValue string
+
+		Default bool // Whether the value parses by default.
+		Literal bool // Whether it parses with WithQueryPlusLiteral.
+	}{
+		{Name: "encoded-space", RawValue: strings.ReplaceAll(spaced, " ", "%20"), Default: true, Literal: true},
+		{Name: "plus-as-space", RawValue: strings.ReplaceAll(spaced, " ", "+"), Default: true, Literal: false},
+		{Name: "literal-plus", RawValue: rfc3339, Default: false, Literal: true},
+		{Name: "encoded-plus", RawValue: strings.ReplaceAll(rfc3339, "+", "%2B"), Default: true, Literal: true},
+	} {
+		for _, literal := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%v/literal=%v", test.Name, literal), func(t *testing.T) {
+				opts := []Option{WithClock(func() time.Time { return now }), WithLayout(time.RFC3339)}
+				want := test.Default
+				if literal {
+					opts, want = append(opts, WithQueryPlusLiteral()), test.Literal
+				}
+				var spy spyHandler
+				h := FromQueryParams("deadline", &spy, opts...)
+				req := httptest.NewRequest("GET", "/?q=earl+grey&deadline="+test.RawValue, nil)
+				h.ServeHTTP(httptest.NewRecorder(), req)
+				if got := spy.OK && spy.Deadline.Equal(deadline); got != want {
+					t.Errorf("deadline applied = %v (%v, %v), want %v", got, spy.Deadline, spy.OK, want)
+				}
+			})
+		}
+	}
+}
+
+func TestLookupRawQuery(t *testing.T) {
+	for _, test := range []struct {
+		RawQuery string
+
+		Val string
+		OK  bool
+		Err bool
+	}{
+		{RawQuery: "", Val: "", OK: false},
+		{RawQuery: "q=a+b", Val: "", OK: false},
+		{RawQuery: "q=a+b&deadline=1+2", Val: "1+2", OK: true},
+		{RawQuery: "deadline=1%202&deadline=3", Val: "1 2", OK: true},
+		{RawQuery: "dead%6Cine=x", Val: "x", OK: true},
+		{RawQuery: "deadline", Val: "", OK: true},
+		{RawQuery: "deadline=%zz", OK: true, Err: true},
+	} {
+		val, ok, err := lookupRawQuery(test.RawQuery, "deadline")
+		if val != test.Val || ok != test.OK || (err != nil) != test.Err {
+			t.Errorf("lookupRawQuery(%q) = %q, %v, %v; want %q, %v, error %v", test.RawQuery, val, ok, err, test.Val, test.OK, test.Err)
+		}
+	}
+}
+
+func BenchmarkApplyFromValues(b *testing.B) {
+	const query = "q=earl+grey&sugar=2&milk=oat&cup=mug&steep=4m&temp=95&deadline="
+	val := url.QueryEscape(asTimeFormat(time.Now().Add(time.Hour)))
+	policy, err := NewPolicy()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("FromQueryParams", func(b *testing.B) {
+		h := FromQueryParams("deadline", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}), policy)
+		req := httptest.NewRequest("GET", "/teapotz?"+query+val, nil)
+		if err := req.ParseForm(); err != nil {
+			b.Fatal(err)
+		}
+		var w discardWriter
+		b.ReportAllocs()
+		b.ResetTimer()
+		for range b.N {
+			h.ServeHTTP(&w, req)
+		}
+	})
+	b.Run("ApplyFromValues", func(b *testing.B) {
+		req := httptest.NewRequest("GET", "/teapotz?"+query+val, nil)
+		if err := req.ParseForm(); err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for range b.N {
+			_, cancel, _ := ApplyFromValues(req.Form, "deadline", req, policy)
+			cancel()
+		}
+	})
+}
+
+func TestApplyFromValuesAdvisory(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	values := url.Values{"deadline": {time.Now().Add(-time.Second).Format(time.RFC3339Nano)}}
+	got, cancel, err := ApplyFromValues(values, "deadline", req, WithLayout(time.RFC3339Nano), WithAdvisoryOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+	if _, ok := got.Context().Deadline(); ok {
+		t.Error("request context has a deadline, want none")
+	}
+	if _, ok := Deadline(got.Context()); !ok {
+		t.Error("Deadline() reports none, want one")
+	}
+}
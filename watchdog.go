@@ -0,0 +1,143 @@
+package httpdeadline
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// WatchdogHandler enforces the deadline on h, rather than merely applying it,
+// with a watchdog goroutine: if the deadline passes before h returns, the
+// watchdog answers the request with 504 Gateway Timeout.  This is a
+// lightweight alternative to buffering enforcement like
+// [http.TimeoutHandler], suited to handlers that write their response only
+// once they finish their work.  Its limitations:
+//
+//   - h keeps running until it returns; the watchdog cannot stop it.  It
+//     should observe its request's context.
+//   - If h began writing its response before the deadline, the watchdog
+//     cannot replace it and does nothing.
+//   - Once the watchdog answered, h's writes and flushes fail with
+//     [http.ErrHandlerTimeout].
+//   - Of the [http.ResponseController] operations, h may only flush; the
+//     others (e.g., hijacking) would escape the watchdog's serialization and
+//     report [http.ErrNotSupported].
+//
+// Writes by h and the watchdog are serialized, and h sees its own header map
+// until it writes, so the two never race.  The watchdog goroutine exits
+// before WatchdogHandler returns.  Deadlines that do not cancel the request's
+// context (see [WithStreamingMode]) are not enforced.
+//
+// WatchdogHandler otherwise behaves like [FromHeader].
+func WatchdogHandler(name string, h http.Handler, opts ...Option) http.Handler {
+	return FromHeader(name, &watchdog{next: h}, opts...)
+}
+
+// A watchdog enforces the deadline of requests on next.
+type watchdog struct {
+	next http.Handler
+}
+
+func (wd *watchdog) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	if _, ok := ctx.Deadline(); !ok {
+		wd.next.ServeHTTP(w, req)
+		return
+	}
+	ww := &watchdogWriter{w: w, header: w.Header().Clone()}
+	done, exited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-done:
+		case <-ctx.Done():
+			if context.Cause(ctx) == ErrDeadlineExceeded {
+				ww.timeout()
+			}
+		}
+	}()
+	defer func() {
+		close(done)
+		<-exited
+	}()
+	wd.next.ServeHTTP(ww, req)
+}
+
+// A watchdogWriter serializes a handler's writes with the watchdog's.  It
+// deliberately does not implement Unwrap, as writing to the underlying
+// http.ResponseWriter would bypass it.
+type watchdogWriter struct {
+	w      http.ResponseWriter
+	header http.Header // The handler's until it writes.
+
+	mu       sync.Mutex
+	wrote    bool
+	timedOut bool
+}
+
+func (w *watchdogWriter) Header() http.Header {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.wrote {
+		return w.w.Header() // For trailers.
+	}
+	return w.header
+}
+
+// commit copies the handler's header to the underlying http.ResponseWriter
+// before its first write.  w.mu must be held.
+func (w *watchdogWriter) commit() error {
+	if w.timedOut {
+		return http.ErrHandlerTimeout
+	}
+	if !w.wrote {
+		w.wrote = true
+		dst := w.w.Header()
+		clear(dst)
+		for k, v := range w.header {
+			dst[k] = v
+		}
+	}
+	return nil
+}
+
+func (w *watchdogWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.commit() == nil {
+		w.w.WriteHeader(code)
+	}
+}
+
+func (w *watchdogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.commit(); err != nil {
+		return 0, err
+	}
+	return w.w.Write(p)
+}
+
+func (w *watchdogWriter) FlushError() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.commit(); err != nil {
+		return err
+	}
+	return http.NewResponseController(w.w).Flush()
+}
+
+func (w *watchdogWriter) Flush() { w.FlushError() }
+
+// timeout answers the request with 504 Gateway Timeout unless the handler
+// already began its response.
+func (w *watchdogWriter) timeout() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.wrote {
+		return
+	}
+	w.timedOut = true
+	http.Error(w.w, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
+	http.NewResponseController(w.w).Flush()
+}
@@ -0,0 +1,109 @@
+package httpdeadline
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWatchdogHandler(t *testing.T) {
+	for _, test := range []struct {
+		Name    string
+		Handler http.HandlerFunc
+
+		Status   int
+		Body     string
+		WriteErr error
+	}{
+		{
+			Name: "handler-first",
+			Handler: func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Write([]byte("tea"))
+			},
+			Status: http.StatusOK,
+			Body:   "tea",
+		},
+		{
+			Name: "deadline-first",
+			Handler: func(w http.ResponseWriter, req *http.Request) {
+				<-req.Context().Done()
+				time.Sleep(10 * time.Millisecond) // Let the watchdog answer.
+				w.Header().Set("Content-Type", "text/plain")
+				if _, err := w.Write([]byte("tea")); !errors.Is(err, http.ErrHandlerTimeout) {
+					t.Errorf("w.Write() = _, %v; want %v", err, http.ErrHandlerTimeout)
+				}
+			},
+			Status: http.StatusGatewayTimeout,
+			Body:   "Gateway Timeout\n",
+		},
+		{
+			Name: "written-before-deadline",
+			Handler: func(w http.ResponseWriter, req *http.Request) {
+				w.Write([]byte("te"))
+				<-req.Context().Done()
+				time.Sleep(10 * time.Millisecond)
+				w.Write([]byte("a"))
+			},
+			Status: http.StatusOK,
+			Body:   "tea",
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			h := WatchdogHandler("X-MTP-Deadline", test.Handler, WithLayout(time.RFC3339Nano))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-MTP-Deadline", time.Now().Add(50*time.Millisecond).Format(time.RFC3339Nano))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, test.Status; got != want {
+				t.Errorf("rec.Code = %v, want %v", got, want)
+			}
+			if got, want := rec.Body.String(), test.Body; got != want {
+				t.Errorf("rec.Body = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestWatchdogHandlerAbsent(t *testing.T) {
+	var spy spyHandler
+	h := WatchdogHandler("X-MTP-Deadline", &spy)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if got, want := rec.Code, http.StatusOK; got != want {
+		t.Errorf("rec.Code = %v, want %v", got, want)
+	}
+	if spy.OK {
+		t.Errorf("spy.Deadline = %v, %v; want none", spy.Deadline, spy.OK)
+	}
+}
+
+func TestWatchdogHandlerFlusher(t *testing.T) {
+	var flusher bool
+	h := WatchdogHandler("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, flusher = w.(http.Flusher)
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-MTP-Deadline", asTimeFormat(time.Now().Add(time.Hour)))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if !flusher {
+		t.Error("writer does not implement http.Flusher")
+	}
+}
+
+func TestWatchdogHandlerNoLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+	h := WatchdogHandler("X-MTP-Deadline", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}), WithLayout(time.RFC3339Nano))
+	for range 100 {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-MTP-Deadline", time.Now().Add(time.Hour).Format(time.RFC3339Nano))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	// Watchdogs exit before ServeHTTP returns, so no settling is needed.
+	if got, want := runtime.NumGoroutine(), before; got > want {
+		t.Errorf("runtime.NumGoroutine() = %v, want at most %v", got, want)
+	}
+}
@@ -0,0 +1,57 @@
+package httpdeadline
+
+import (
+	"net/http"
+	"time"
+)
+
+// WithOnFirstWrite calls fn when the wrapped handler first writes to (or
+// flushes) its response, reporting how long after the request's deadline was
+// applied that happened and the budget applied to the request, which signals
+// how much of the budget handlers consume before the first byte.  fn is called
+// synchronously from the handler's write, so it should be fast.  It is not
+// called if the handler writes nothing.
+func WithOnFirstWrite(fn func(req *http.Request, sinceStart, budget time.Duration)) Option {
+	if fn == nil {
+		return invalidf("nil first write func")
+	}
+	return optionFunc(func(c *config) { c.onFirstWrite = fn })
+}
+
+// A writeTracker records whether anything was written to its
+// http.ResponseWriter.
+type writeTracker struct {
+	http.ResponseWriter
+	wrote        bool
+	onFirstWrite []func()
+}
+
+func (w *writeTracker) written() {
+	if w.wrote {
+		return
+	}
+	w.wrote = true
+	for _, fn := range w.onFirstWrite {
+		fn()
+	}
+}
+
+func (w *writeTracker) WriteHeader(code int) {
+	w.written()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *writeTracker) Write(p []byte) (int, error) {
+	w.written()
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *writeTracker) FlushError() error {
+	w.written() // Flushing commits the headers.
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *writeTracker) Flush() { w.FlushError() }
+
+// Unwrap lets [http.ResponseController] reach the underlying writer.
+func (w *writeTracker) Unwrap() http.ResponseWriter { return w.ResponseWriter }
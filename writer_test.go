@@ -0,0 +1,74 @@
+package httpdeadline
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithOnFirstWrite(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	var (
+		calls      int
+		sinceStart time.Duration
+		budget     time.Duration
+	)
+	onFirstWrite := func(req *http.Request, since, b time.Duration) {
+		calls++
+		sinceStart, budget = since, b
+	}
+	h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(delay)
+		w.Write([]byte("hello"))
+		w.Write([]byte(", world"))
+	}), WithLayout(time.RFC3339Nano), WithOnFirstWrite(onFirstWrite))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-MTP-Deadline", time.Now().Add(time.Second).Format(time.RFC3339Nano))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if got, want := calls, 1; got != want {
+		t.Fatalf("calls = %v, want %v", got, want)
+	}
+	if sinceStart < delay || sinceStart > delay+500*time.Millisecond {
+		t.Errorf("sinceStart = %v, want in [%v, %v]", sinceStart, delay, delay+500*time.Millisecond)
+	}
+	if budget <= 0 || budget > time.Second {
+		t.Errorf("budget = %v, want in (0, %v]", budget, time.Second)
+	}
+}
+
+func TestWithOnFirstWriteUnwritten(t *testing.T) {
+	var called bool
+	h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}),
+		WithLayout(time.RFC3339Nano), WithOnFirstWrite(func(*http.Request, time.Duration, time.Duration) { called = true }))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-MTP-Deadline", time.Now().Add(time.Second).Format(time.RFC3339Nano))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if called {
+		t.Error("first write func called for handler that wrote nothing")
+	}
+}
+
+func TestWriteTrackerFlusher(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		Opt  Option
+	}{
+		{Name: "first-write", Opt: WithOnFirstWrite(func(*http.Request, time.Duration, time.Duration) {})},
+		{Name: "grpc-web", Opt: WithGRPCWebStatus()},
+		{Name: "trailer", Opt: WithDeadlineTrailer("X-MTP-Deadline-Remaining")},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var flusher bool
+			h := FromHeader("X-MTP-Deadline", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				_, flusher = w.(http.Flusher)
+			}), test.Opt)
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-MTP-Deadline", asTimeFormat(time.Now().Add(time.Hour)))
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if !flusher {
+				t.Error("writer does not implement http.Flusher")
+			}
+		})
+	}
+}